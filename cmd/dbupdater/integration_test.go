@@ -1,7 +1,12 @@
 package main_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -10,10 +15,14 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
-	"dbupdater/config"
-	"dbupdater/helper"
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/core"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/updater"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/joho/godotenv"
@@ -27,7 +36,7 @@ var (
 
 // It is necessary to have a folder in the folder with the project
 // pg_dump_restore_15_2 folder, which should contain the utilities
-// pg_dump and pg_restore
+// pg_dump and pg_restore. Or the utilities must be in $PATH or in the well-known directories.
 
 // The parameters in the test.env file are required
 // DB_HOST
@@ -171,14 +180,41 @@ func crateTestDatabase(ctx context.Context, conn *pgx.Conn) (string, error) {
 
 func TestOpenConnectToDatabase(t *testing.T) {
 	t.Parallel()
-	t.Run("NoAllParametersForConnect", func(t *testing.T) {
+	t.Run("ConnectionStringURL", func(t *testing.T) {
 		t.Parallel()
-		badConnectString := fmt.Sprintf("-host %s -port %s -dbname %s -username %s",
-			entryForTestDatabase.Host, entryForTestDatabase.Port, entryForTestDatabase.DbName, entryForTestDatabase.User)
+		clearTmpDir := t.TempDir()
+		dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=prefer", entryForTestDatabase.User, entryForTestDatabase.Password,
+			entryForTestDatabase.Host, entryForTestDatabase.Port, entryForTestDatabase.DbName)
 
-		output := runUtility(t, badConnectString)
-		if !strings.Contains(output, "Not all parameters for connection are specified.") {
-			t.Errorf("There is no message that not all parameters for connection are specified")
+		output := runUtility(t, "-dsn "+dsn+" -migrations "+clearTmpDir)
+		if !strings.Contains(output, "Initialization mode - ON") {
+			t.Errorf("Failed to join the database with the connection URL: %s", output)
+		}
+	})
+
+	t.Run("ConnectionStringKeywords", func(t *testing.T) {
+		t.Parallel()
+		clearTmpDir := t.TempDir()
+		// The spaces separate the parameters of the utility, so there is only one keyword in the connection string
+		output := runUtility(t, "-dsn sslmode=prefer -host "+entryForTestDatabase.Host+" -port "+entryForTestDatabase.Port+
+			" -dbname "+entryForTestDatabase.DbName+" -username "+entryForTestDatabase.User+
+			" -password "+entryForTestDatabase.Password+" -migrations "+clearTmpDir)
+		if !strings.Contains(output, "Initialization mode - ON") {
+			t.Errorf("Failed to join the database with the keyword connection string: %s", output)
+		}
+	})
+
+	// The flags override the values from the connection string
+	t.Run("FlagsOverrideConnectionString", func(t *testing.T) {
+		t.Parallel()
+		clearTmpDir := t.TempDir()
+		dsn := fmt.Sprintf("postgres://%s:badPassword@%s:%s/no_such_database", entryForTestDatabase.User,
+			entryForTestDatabase.Host, entryForTestDatabase.Port)
+
+		output := runUtility(t, "-dsn "+dsn+" -dbname "+entryForTestDatabase.DbName+
+			" -password "+entryForTestDatabase.Password+" -migrations "+clearTmpDir)
+		if !strings.Contains(output, "Initialization mode - ON") {
+			t.Errorf("The flags have not overridden the connection string: %s", output)
 		}
 	})
 
@@ -199,7 +235,7 @@ func TestOpenConnectToDatabase(t *testing.T) {
 		t.Parallel()
 		clearTmpDir := t.TempDir()
 		output := runUtility(t, connectString+` -migrations `+clearTmpDir)
-		if !strings.Contains(output, "GetCurrentVersion.sql: The system cannot find the path specified.") {
+		if !strings.Contains(output, "Initialization mode - ON") {
 			t.Errorf("Failed to join the database with correct connection parameters")
 		}
 	})
@@ -219,7 +255,7 @@ func TestGetCurrentMigration(t *testing.T) {
 	t.Run("GetCurrentVersionFileNoIsExists", func(t *testing.T) {
 		t.Parallel()
 		clearTmpDir := t.TempDir()
-		output := runUtility(t, connectString+` -migrations `+clearTmpDir)
+		output := runUtility(t, connectString+` -migrations `+clearTmpDir+` -tracking legacy`)
 		if !strings.Contains(output, "GetCurrentVersion.sql: The system cannot find the path specified.") {
 			t.Errorf("No error if GetCurrentVersion.sql is missing")
 		}
@@ -1591,65 +1627,1818 @@ func TestInitMod(t *testing.T) {
 	})
 }
 
-// ---------------------------------------------------------------FUNCTIONS-------------------------------------------------------------------------------------
+func TestHistoryTracking(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
 
-// Starts the utility with the passed parameters
-func runUtility(t *testing.T, parameters string) string {
-	t.Helper()
-	params := strings.Split(parameters, " ")
-	cmd := exec.Command(pathToUtility, params...)
-	output, _ := cmd.CombinedOutput()
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS history_test; DROP TABLE IF EXISTS historyTracking;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
 
-	return string(output)
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table historyTracking ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.InsertData.sql`, "insert into historyTracking values (1);")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0002.InsertMoreData.sql`, "insert into historyTracking values (2);")
+	historyParameters := ` -migrations ` + tmpDir + ` -historytable history_test`
+
+	t.Run("InitModeWithoutHistoryTable", func(t *testing.T) {
+		output := runUtility(t, connectString+historyParameters+` -versiondb v0.0.1 -migration 0001.InsertData`)
+
+		correctOrder := isCorrectOrder(output, "Initialization mode - ON", "Updates available:",
+			"0001.CreateTable", "0001.InsertData", "Migrations have been applied.")
+		if !correctOrder {
+			t.Errorf("The initialization mode must be running and the migrations must be applied")
+		}
+
+		rows, err := conn.Query(ctx, "SELECT version_db, name, operation, checksum FROM history_test ORDER BY id")
+		if err != nil {
+			t.Fatalf("Error when Query: %v", err)
+		}
+		defer rows.Close()
+
+		applied := make([]string, 0)
+		for rows.Next() {
+			var versionDb, name, operation, checksum string
+			if err := rows.Scan(&versionDb, &name, &operation, &checksum); err != nil {
+				t.Fatalf("Error when Scan: %v", err)
+			}
+			if operation != "apply" || len(checksum) != 64 {
+				t.Errorf("Wrong operation or checksum in the history: %s %s", operation, checksum)
+			}
+			applied = append(applied, versionDb+" "+name)
+		}
+		if strings.Join(applied, ", ") != "v0.0.0 0001.CreateTable, v0.0.1 0001.InsertData" {
+			t.Errorf("Wrong rows in the history: %v", applied)
+		}
+	})
+
+	t.Run("CurrentMigrationFromHistory", func(t *testing.T) {
+		output := runUtility(t, connectString+historyParameters)
+
+		correctOrder := isCorrectOrder(output, "Current database version: v0.0.1",
+			"Last migration applied: 0001.InsertData", "Updates available:", "0002.InsertMoreData")
+		if !correctOrder || strings.Contains(output, "Initialization mode - ON") {
+			t.Errorf("The current migration must be taken from the last row of the history")
+		}
+	})
+
+	t.Run("ImportLegacyIntoNotEmptyHistory", func(t *testing.T) {
+		createFileAndWrite(t, tmpDir+`/utils/GetCurrentVersion.sql`, "SELECT 'v0.0.1' as version_db, '0002.InsertMoreData' as name")
+		defer removeAll(t, tmpDir+`/utils`)
+
+		output := runUtility(t, `import-legacy `+connectString+historyParameters)
+		if !strings.Contains(output, "the import is possible only into an empty history") {
+			t.Errorf("The import into a not empty history must be refused")
+		}
+	})
 }
 
-func createFileAndWrite(t *testing.T, path string, content string) {
-	t.Helper()
-	createDir(t, filepath.Dir(path))
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0o600)
+func TestImportLegacy(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
 	if err != nil {
-		t.Fatalf("File creation error: %v", err)
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
 	}
-	defer file.Close()
+	defer conn.Close(ctx)
 
-	if _, err := file.WriteString(content); err != nil {
-		t.Fatalf("Error writing to file: %v", err)
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS import_history_test;"); err != nil {
+			t.Fatalf("Error when deleting a test table: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/utils/GetCurrentVersion.sql`, "SELECT 'v0.0.3' as version_db, '0002.Second' as name")
+	createFileAndWrite(t, tmpDir+`/v0.0.3/0001.First.sql`, "")
+	createFileAndWrite(t, tmpDir+`/v0.0.3/0002.Second.sql`, "")
+	createFileAndWrite(t, tmpDir+`/v0.0.3/0003.Third.sql`, "")
+
+	output := runUtility(t, `import-legacy `+connectString+` -migrations `+tmpDir+` -historytable import_history_test`)
+	if !strings.Contains(output, "The v0.0.3 0002.Second migration has been imported into import_history_test") {
+		t.Errorf("There should be a message about the successful import")
+	}
+
+	var versionDb, name, operation string
+	if err := conn.QueryRow(ctx, "SELECT version_db, name, operation FROM import_history_test").Scan(&versionDb, &name, &operation); err != nil {
+		t.Fatalf("Error when QueryRow: %v", err)
+	}
+	if versionDb != "v0.0.3" || name != "0002.Second" || operation != "import" {
+		t.Errorf("Wrong imported row: %s %s %s", versionDb, name, operation)
+	}
+
+	removeAll(t, tmpDir+`/utils`)
+	output = runUtility(t, connectString+` -migrations `+tmpDir+` -historytable import_history_test`)
+	correctOrder := isCorrectOrder(output, "Current database version: v0.0.3", "Last migration applied: 0002.Second", "0003.Third")
+	if !correctOrder {
+		t.Errorf("After the import, the current migration must be taken from the history")
 	}
 }
 
-func createDir(t *testing.T, path string) {
-	t.Helper()
-	if err := os.MkdirAll(path, 0o600); err != nil {
-		t.Fatalf("Error when creating directories for a file: %v", err)
+func TestMigrateInTransaction(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
 	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS transaction_history_test; DROP TABLE IF EXISTS transactionTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table transactionTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.InsertData.sql`, "insert into transactionTest values (1);")
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0001.InsertData.sql`, "insert into transactionTest values (2);")
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0002.Wrong.sql`, "insert into tableThatDoesNotExist values (3);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable transaction_history_test`
+
+	t.Run("ErrorInOneTransaction", func(t *testing.T) {
+		output := runUtility(t, connectString+parameters+` -versiondb v0.0.2 -rollback transaction`)
+
+		correctOrder := isCorrectOrder(output, "Migrations started to apply...", "Error applying migration:",
+			`relation "tablethatdoesnotexist" does not exist`, "The transaction has been rolled back.")
+		if !correctOrder || strings.Contains(output, "Dump created.") {
+			t.Errorf("The transaction must be rolled back without a dump")
+		}
+
+		var isExistsTable bool
+		if err := conn.QueryRow(ctx, "SELECT to_regclass('transactionTest') IS NOT NULL").Scan(&isExistsTable); err != nil {
+			t.Fatalf("Error when QueryRow: %v", err)
+		}
+		if isExistsTable {
+			t.Errorf("The migrations from the rolled back transaction must not remain in the database")
+		}
+	})
+
+	t.Run("ErrorInTransactionPerVersion", func(t *testing.T) {
+		output := runUtility(t, connectString+parameters+` -versiondb v0.0.2 -rollback transaction-per-version`)
+
+		correctOrder := isCorrectOrder(output, "Error applying migration:", "The transaction has been rolled back.",
+			"The migrations up to v0.0.1 0001.InsertData inclusive remain applied.")
+		if !correctOrder {
+			t.Errorf("Only the transaction of the version with the error must be rolled back")
+		}
+
+		var count int
+		if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM transactionTest").Scan(&count); err != nil {
+			t.Fatalf("Error when QueryRow: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("The data of v0.0.1 must remain, the data of v0.0.2 must be rolled back, rows: %d", count)
+		}
+
+		output = runUtility(t, connectString+parameters)
+		if !isCorrectOrder(output, "Current database version: v0.0.1", "Last migration applied: 0001.InsertData") {
+			t.Errorf("The current migration must be the last one of the committed version")
+		}
+	})
 }
 
-func removeAll(t *testing.T, path string) {
-	t.Helper()
-	if err := os.RemoveAll(path); err != nil {
-		t.Fatalf("Deletion error: %v", err)
+func TestNoTransactionMigration(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS no_transaction_history_test; DROP TABLE IF EXISTS noTransactionTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table noTransactionTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0002.CreateIndex.sql`, "-- Index without locking the table\n"+
+		"-- dbupdater:no-transaction\n"+
+		"create index concurrently noTransactionTestIndex on noTransactionTest (id);")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0003.InsertData.sql`, "insert into noTransactionTest values (1);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable no_transaction_history_test`
+
+	output := runUtility(t, connectString+parameters+` -versiondb v0.0.0 -rollback transaction -verbose`)
+
+	correctOrder := isCorrectOrder(output, "Updates available:", "0002.CreateIndex (no transaction)",
+		"The transaction has been committed.", "The v0.0.0 0002.CreateIndex migration cannot be executed inside a transaction",
+		"Dump created.", "Migrations have been applied.")
+	if !correctOrder {
+		t.Errorf("The migration marked as no-transaction must be applied outside a transaction with a dump before it")
+	}
+
+	var isExistsIndex bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('noTransactionTestIndex') IS NOT NULL").Scan(&isExistsIndex); err != nil {
+		t.Fatalf("Error when QueryRow: %v", err)
+	}
+	if !isExistsIndex {
+		t.Errorf("The index must be created")
 	}
 }
 
-// Checks that all strings are in the string and that they follow each other
-func isCorrectOrder(input string, args ...string) bool {
-	lastIndex := -1
-	for _, str := range args {
-		newIndex := strings.Index(input, str)
-		if newIndex == -1 || newIndex <= lastIndex {
-			return false
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS rollback_history_test; DROP TABLE IF EXISTS rollbackTest1; DROP TABLE IF EXISTS rollbackTest2;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
 		}
-		lastIndex = newIndex
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTest1.sql`, "create table rollbackTest1 ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTest1.down.sql`, "drop table rollbackTest1;")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTest2.sql`, "create table rollbackTest2 ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTest2.down.sql`, "drop table rollbackTest2;")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0002.InsertTest2.sql`, "insert into rollbackTest2 values (1);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable rollback_history_test`
+
+	output := runUtility(t, connectString+parameters+` -versiondb v0.0.1`)
+	if !strings.Contains(output, "Migrations have been applied.") {
+		t.Fatalf("Migrations must be applied: %s", output)
+	}
+
+	output = runUtility(t, `rollback `+connectString+parameters+` -versiondb v0.0.0`)
+	if !strings.Contains(output, "there are no down scripts for the migrations: v0.0.1 0002.InsertTest2") {
+		t.Errorf("The rollback must be refused if there is no down script: %s", output)
+	}
+
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0002.InsertTest2.down.sql`, "delete from rollbackTest2;")
+	output = runUtility(t, `rollback `+connectString+parameters+` -versiondb v0.0.0 -rollback transaction`)
+	correctOrder := isCorrectOrder(output, "Migrations to roll back:", "v0.0.1", "0002.InsertTest2", "0001.CreateTest2",
+		"After the rollback the current migration will be v0.0.0 0001.CreateTest1", "Migrations started to roll back...",
+		"Reverted: ", "v0.0.1 0002.InsertTest2", "Reverted: ", "v0.0.1 0001.CreateTest2", "Migrations have been rolled back.")
+	if !correctOrder {
+		t.Errorf("Migrations must be rolled back in reverse order: %s", output)
+	}
+
+	var isExistsTest2 bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('rollbackTest2') IS NOT NULL").Scan(&isExistsTest2); err != nil {
+		t.Fatalf("Error when QueryRow: %v", err)
+	}
+	if isExistsTest2 {
+		t.Errorf("The rollbackTest2 table must be deleted by the down script")
+	}
+
+	output = runUtility(t, connectString+parameters)
+	if !isCorrectOrder(output, "Current database version: v0.0.0", "Last migration applied: 0001.CreateTest1", "v0.0.1") {
+		t.Errorf("After the rollback the current migration must be v0.0.0 0001.CreateTest1: %s", output)
 	}
-	return true
 }
 
-func isNotContainsAll(input string, args ...string) bool {
-	for _, str := range args {
-		isContains := strings.Contains(input, str)
-		if isContains {
-			return false
+func TestRollbackNoTransaction(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS rollback_no_transaction_history_test; DROP TABLE IF EXISTS rollbackNoTransactionTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
 		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table rollbackNoTransactionTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0002.CreateIndex.sql`, "-- dbupdater:no-transaction\n"+
+		"create index concurrently rollbackNoTransactionTestIndex on rollbackNoTransactionTest (id);")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0002.CreateIndex.down.sql`, "-- dbupdater:no-transaction\n"+
+		"drop index concurrently rollbackNoTransactionTestIndex;")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0003.InsertData.sql`, "insert into rollbackNoTransactionTest values (1);")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0003.InsertData.down.sql`, "delete from rollbackNoTransactionTest;")
+	parameters := ` -migrations ` + tmpDir + ` -historytable rollback_no_transaction_history_test`
+
+	output := runUtility(t, `up `+connectString+parameters+` -rollback transaction`)
+	if !strings.Contains(output, "Migrations have been applied.") {
+		t.Fatalf("Migrations must be applied: %s", output)
+	}
+
+	output = runUtility(t, `rollback `+connectString+parameters+` -versiondb v0.0.0 -migration 0001.CreateTable -rollback transaction -verbose`)
+	correctOrder := isCorrectOrder(output, "0003.InsertData", "0002.CreateIndex (without a transaction)",
+		"Reverted: ", "v0.0.0 0003.InsertData", "The transaction has been committed.",
+		"The down script of the v0.0.0 0002.CreateIndex migration cannot be executed inside a transaction", "Dump created.",
+		"Reverted: ", "v0.0.0 0002.CreateIndex")
+	if !correctOrder {
+		t.Errorf("The down script marked as no-transaction must be executed outside a transaction with a dump before it: %s", output)
+	}
+
+	var isExistsIndex bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('rollbackNoTransactionTestIndex') IS NOT NULL").Scan(&isExistsIndex); err != nil {
+		t.Fatalf("Error when QueryRow: %v", err)
+	}
+	if isExistsIndex {
+		t.Errorf("The index must be dropped by the down script")
+	}
+
+	output = runUtility(t, `status `+connectString+parameters)
+	if !isCorrectOrder(output, "Current database version: v0.0.0", "Last migration applied: 0001.CreateTable") {
+		t.Errorf("After the rollback the current migration must be v0.0.0 0001.CreateTable: %s", output)
+	}
+}
+
+func TestRollbackSkipped(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS rollback_skipped_history_test; DROP TABLE IF EXISTS rollbackSkippedTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table rollbackSkippedTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0002.BadInsert.sql`, "insert into rollbackSkippedTest valuez (2);")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0002.BadInsert.down.sql`, "drop table rollbackSkippedTest;")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0003.InsertData.sql`, "insert into rollbackSkippedTest values (3);")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0003.InsertData.down.sql`, "delete from rollbackSkippedTest;")
+	parameters := ` -migrations ` + tmpDir + ` -historytable rollback_skipped_history_test -rollback transaction`
+
+	runUtility(t, `up `+connectString+parameters+` -versiondb v0.0.0 -migration 0001.CreateTable`)
+	runUtility(t, `skip `+connectString+parameters+` -reason INC-8`)
+	output := runUtility(t, `up `+connectString+parameters)
+	if !strings.Contains(output, "Migrations have been applied.") {
+		t.Fatalf("The migrations after the skipped one must be applied: %s", output)
+	}
+
+	output = runUtility(t, `rollback `+connectString+parameters+` -versiondb v0.0.0 -migration 0001.CreateTable`)
+	correctOrder := isCorrectOrder(output, "0003.InsertData", "0002.BadInsert (skipped, the down script is not executed)",
+		"Passed over: v0.0.0 0002.BadInsert, it has been skipped, the down script is not executed", "Migrations have been rolled back.")
+	if !correctOrder {
+		t.Errorf("The down script of the skipped migration must not be executed: %s", output)
+	}
+
+	count := 0
+	if err := conn.QueryRow(ctx, "select count(*) from rollbackSkippedTest").Scan(&count); err != nil {
+		t.Fatalf("The table must not be dropped by the down script of the skipped migration: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("The migration after the skipped one must be reverted, expected 0 rows, received %d", count)
+	}
+
+	output = runUtility(t, `status `+connectString+parameters)
+	if !isCorrectOrder(output, "Current database version: v0.0.0", "Last migration applied: 0001.CreateTable") {
+		t.Errorf("After the rollback the current migration must be v0.0.0 0001.CreateTable: %s", output)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS verify_history_test; DROP TABLE IF EXISTS verifyTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table verifyTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0002.InsertData.sql`, "insert into verifyTest values (1);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable verify_history_test`
+
+	output := runUtility(t, connectString+parameters+` -versiondb v0.0.0 -migration 0001.CreateTable`)
+	if !strings.Contains(output, "Migrations have been applied.") {
+		t.Fatalf("Migrations must be applied: %s", output)
+	}
+
+	output = runUtility(t, `verify `+connectString+parameters)
+	if !strings.Contains(output, "The files of the applied migrations match the checksums") {
+		t.Errorf("Unchanged files must pass the verification: %s", output)
+	}
+
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "\n-- changed after applying")
+	output = runUtility(t, `verify `+connectString+parameters)
+	if !isCorrectOrder(output, "The files of applied migrations have been changed:", "v0.0.0 0001.CreateTable - recorded checksum",
+		"The files of 1 applied migrations do not match the checksums") {
+		t.Errorf("The changed file must be found by the verification: %s", output)
+	}
+
+	output = runUtility(t, connectString+parameters+` -versiondb v0.0.0`)
+	if !strings.Contains(output, "specify -ignorechecksums to apply anyway") || strings.Contains(output, "Migrations started to apply...") {
+		t.Errorf("Migrations must not be applied if the files of applied migrations have been changed: %s", output)
+	}
+
+	output = runUtility(t, connectString+parameters+` -versiondb v0.0.0 -ignorechecksums`)
+	if !isCorrectOrder(output, "WARNING. The changed files of applied migrations are ignored", "Migrations have been applied.") {
+		t.Errorf("Migrations must be applied with -ignorechecksums: %s", output)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS commands_history_test; DROP TABLE IF EXISTS commandsTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table commandsTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.InsertData.sql`, "insert into commandsTest values (1);")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0002.InsertMoreData.sql`, "insert into commandsTest values (2);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable commands_history_test`
+
+	t.Run("Status", func(t *testing.T) {
+		output := runUtility(t, `status `+connectString+parameters)
+		if !isCorrectOrder(output, "Updates available:", "v0.0.0", "0001.CreateTable", "v0.0.1", "0002.InsertMoreData") ||
+			strings.Contains(output, "Migrations started to apply...") {
+			t.Errorf("status must show the migrations without applying them: %s", output)
+		}
+	})
+
+	t.Run("StatusDoesNotAcceptTarget", func(t *testing.T) {
+		output := runUtility(t, `status `+connectString+parameters+` -versiondb v0.0.1`)
+		if !strings.Contains(output, "flag provided but not defined: -versiondb") {
+			t.Errorf("status must not accept -versiondb: %s", output)
+		}
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		output := runUtility(t, `plan `+connectString+parameters+` -versiondb v0.0.0`)
+		indexPlan := strings.Index(output, "Migrations to apply:")
+		if indexPlan == -1 || !isCorrectOrder(output[indexPlan:], "v0.0.0", "0001.CreateTable", "Rollback strategy: dump") ||
+			strings.Contains(output[indexPlan:], "0001.InsertData") || strings.Contains(output, "Migrations started to apply...") {
+			t.Errorf("plan must show only the migrations up to the target without applying them: %s", output)
+		}
+	})
+
+	t.Run("PlanWithDumpUtilities", func(t *testing.T) {
+		pathToDumpUtility := tmpDir + "/missing/pg_dump"
+		output := runUtility(t, `plan `+connectString+parameters+` -rollback dump -pgdump `+pathToDumpUtility)
+		if !isCorrectOrder(output, "Migrations to apply:", "explicitly specified pg_dump", pathToDumpUtility) {
+			t.Errorf("plan must look for pg_dump in the path specified in -pgdump as up does: %s", output)
+		}
+	})
+
+	t.Run("PlanWithSql", func(t *testing.T) {
+		output := runUtility(t, `plan `+connectString+parameters+` -sql -rollback transaction`)
+		if !isCorrectOrder(output, "Migrations to apply:", "0002.InsertMoreData", "Rollback strategy: transaction",
+			"create table commandsTest ( id int );", "insert into commandsTest values (2);",
+			"After applying the current migration in commands_history_test will be v0.0.1 0002.InsertMoreData") ||
+			strings.Contains(output, "A dump is created") {
+			t.Errorf("plan -sql must show the sql text of the migrations and the current migration after applying: %s", output)
+		}
+	})
+
+	t.Run("PlanWithWrongTarget", func(t *testing.T) {
+		output := runUtility(t, `plan `+connectString+parameters+` -versiondb v9.9.9`)
+		if !strings.Contains(output, "The v9.9.9 version is not in the list of migrations available for updating") {
+			t.Errorf("plan must fail if the target is not in the list of new migrations: %s", output)
+		}
+	})
+
+	t.Run("WithoutCommandMigrationWithoutVersion", func(t *testing.T) {
+		output := runUtility(t, connectString+parameters+` -migration 0001.InsertData`)
+		if !isCorrectOrder(output, "WARNING. Running without a command is deprecated, use 'dbupdater status'.",
+			"WARNING. -migration without -versiondb does not apply migrations", "Updates available:") ||
+			strings.Contains(output, "Migrations started to apply...") {
+			t.Errorf("-migration without -versiondb and without a command must only show the status: %s", output)
+		}
+	})
+
+	t.Run("UpWithoutTarget", func(t *testing.T) {
+		output := runUtility(t, `up `+connectString+parameters+` -rollback transaction`)
+		if !isCorrectOrder(output, "Migrations started to apply...", "v0.0.1 0002.InsertMoreData", "Migrations have been applied.") {
+			t.Errorf("up without -versiondb must apply all new migrations: %s", output)
+		}
+	})
+
+	t.Run("WithoutCommand", func(t *testing.T) {
+		output := runUtility(t, connectString+parameters)
+		if !isCorrectOrder(output, "WARNING. Running without a command is deprecated, use 'dbupdater status'.", "No new migrations") {
+			t.Errorf("The invocation without a command must work as status: %s", output)
+		}
+	})
+}
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS runner_history_test; DROP TABLE IF EXISTS runnerTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table runnerTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0002.InsertData.sql`, "insert into runnerTest values ('not a number');")
+
+	newRunner := func(t *testing.T, stringVersionDb string) *core.Runner {
+		t.Helper()
+		cfg := &config.Config{
+			App: config.App{Version: "test"},
+			Parameters: config.Parameters{
+				Command:          config.CommandUp,
+				PathToMigrations: tmpDir,
+				StringVersionDb:  stringVersionDb,
+				TrackingMode:     config.TrackingHistory,
+				HistoryTable:     "runner_history_test",
+				RollbackStrategy: config.RollbackTransaction,
+			},
+			DbEntry: *entryForTestDatabase,
+		}
+		runner, err := core.NewRunner(ctx, cfg, helper.NewStdPrinter(false))
+		if err != nil {
+			t.Fatalf("Error when creating the runner: %v", err)
+		}
+		return runner
+	}
+
+	t.Run("InvalidTarget", func(t *testing.T) {
+		runner := newRunner(t, "v0.0.7")
+		defer runner.Close(ctx)
+
+		if err := runner.Up(ctx); !errors.Is(err, domain.ErrInvalidTarget) {
+			t.Errorf("Expected domain.ErrInvalidTarget, got: %v", err)
+		}
+	})
+
+	t.Run("ApplyFailed", func(t *testing.T) {
+		runner := newRunner(t, "v0.0.0")
+		defer runner.Close(ctx)
+
+		if err := runner.Up(ctx); !errors.Is(err, domain.ErrApplyFailed) {
+			t.Errorf("Expected domain.ErrApplyFailed, got: %v", err)
+		}
+
+		status, err := runner.Status(ctx)
+		if err != nil {
+			t.Fatalf("Error when getting the status: %v", err)
+		}
+		if !status.IsInitMode || len(status.UnappliedMigrations) != 1 {
+			t.Errorf("After the failed apply the database must stay in initialization mode")
+		}
+	})
+}
+
+func TestUpdater(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS updater_history_test; DROP TABLE IF EXISTS updaterTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table updaterTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.InsertData.sql`, "insert into updaterTest values (1);")
+
+	u, err := updater.New(updater.Connection{
+		Host:     entryForTestDatabase.Host,
+		Port:     entryForTestDatabase.Port,
+		DbName:   entryForTestDatabase.DbName,
+		User:     entryForTestDatabase.User,
+		Password: entryForTestDatabase.Password,
+	},
+		updater.WithMigrationsDir(tmpDir),
+		updater.WithTracking(updater.TrackingHistory),
+		updater.WithHistoryTable("updater_history_test"),
+		updater.WithRollbackStrategy(updater.RollbackTransaction),
+	)
+	if err != nil {
+		t.Fatalf("Error when creating the updater: %v", err)
+	}
+
+	if _, err := updater.New(updater.Connection{}); !errors.Is(err, updater.ErrInvalidParameters) {
+		t.Errorf("Expected updater.ErrInvalidParameters without migrations, got: %v", err)
+	}
+
+	plan, err := u.Plan(ctx, updater.Target{})
+	if err != nil {
+		t.Fatalf("Error when getting the plan: %v", err)
+	}
+	if len(plan) != 2 || plan[1].VersionDb != "v0.0.1" || plan[1].Name != "0001.InsertData" {
+		t.Errorf("The plan must contain all new migrations: %v", plan)
+	}
+
+	if err := u.Up(ctx, updater.Target{VersionDb: "v0.0.9"}); !errors.Is(err, updater.ErrInvalidTarget) {
+		t.Errorf("Expected updater.ErrInvalidTarget, got: %v", err)
+	}
+
+	if err := u.Up(ctx, updater.Target{}); err != nil {
+		t.Fatalf("Error when applying migrations: %v", err)
+	}
+
+	status, err := u.Status(ctx)
+	if err != nil {
+		t.Fatalf("Error when getting the status: %v", err)
+	}
+	if len(status.UnappliedMigrations) != 0 || status.CurrentMigration.VersionDb != "v0.0.1" ||
+		status.CurrentMigration.Name != "0001.InsertData" {
+		t.Errorf("All migrations must be applied: %v", status)
+	}
+
+	var toolVersion string
+	if err := conn.QueryRow(ctx, "SELECT tool_version FROM updater_history_test ORDER BY id DESC LIMIT 1").Scan(&toolVersion); err != nil {
+		t.Fatalf("Error when QueryRow: %v", err)
+	}
+	if toolVersion != config.Version {
+		t.Errorf("The version of dbupdater must be recorded in the history, got: %q", toolVersion)
+	}
+}
+
+func TestMigrationsSources(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS sources_history_test; DROP TABLE IF EXISTS sourcesTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	files := map[string]string{
+		"migrations/v0.0.0/0001.CreateTable.sql": "create table sourcesTest ( id int );",
+		"migrations/v0.0.1/0001.InsertData.sql":  "insert into sourcesTest values (1);",
+	}
+	tmpDir := t.TempDir()
+	parameters := ` -historytable sources_history_test`
+
+	t.Run("Zip", func(t *testing.T) {
+		pathToArchive := tmpDir + "/migrations.zip"
+		createZip(t, pathToArchive, files)
+		output := runUtility(t, `status `+connectString+` -migrations `+pathToArchive+parameters)
+		if !isCorrectOrder(output, "Updates available:", "v0.0.0", "0001.CreateTable", "v0.0.1", "0001.InsertData") {
+			t.Errorf("The migrations must be read from the .zip archive: %s", output)
+		}
+	})
+
+	t.Run("TarGz", func(t *testing.T) {
+		pathToArchive := tmpDir + "/migrations.tar.gz"
+		createTarGz(t, pathToArchive, files)
+		output := runUtility(t, `status `+connectString+` -migrations `+pathToArchive+parameters)
+		if !isCorrectOrder(output, "Updates available:", "v0.0.0", "0001.CreateTable", "v0.0.1", "0001.InsertData") {
+			t.Errorf("The migrations must be read from the .tar.gz archive: %s", output)
+		}
+	})
+
+	t.Run("FS", func(t *testing.T) {
+		migrationsFS := fstest.MapFS{}
+		for name, content := range files {
+			migrationsFS[strings.TrimPrefix(name, "migrations/")] = &fstest.MapFile{Data: []byte(content)}
+		}
+		u, err := updater.New(updater.Connection{
+			Host:     entryForTestDatabase.Host,
+			Port:     entryForTestDatabase.Port,
+			DbName:   entryForTestDatabase.DbName,
+			User:     entryForTestDatabase.User,
+			Password: entryForTestDatabase.Password,
+		},
+			updater.WithMigrationsFS(migrationsFS),
+			updater.WithHistoryTable("sources_history_test"),
+			updater.WithRollbackStrategy(updater.RollbackTransaction),
+		)
+		if err != nil {
+			t.Fatalf("Error when creating the updater: %v", err)
+		}
+
+		if err := u.Up(ctx, updater.Target{}); err != nil {
+			t.Fatalf("Error when applying migrations from fs.FS: %v", err)
+		}
+		status, err := u.Status(ctx)
+		if err != nil {
+			t.Fatalf("Error when getting the status: %v", err)
+		}
+		if len(status.UnappliedMigrations) != 0 || status.CurrentMigration.VersionDb != "v0.0.1" {
+			t.Errorf("All migrations from fs.FS must be applied: %v", status)
+		}
+	})
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS lock_history_test; DROP TABLE IF EXISTS lockTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.0/0001.CreateTable.sql`, "create table lockTest ( id int );")
+	parameters := ` -migrations ` + tmpDir + ` -historytable lock_history_test -rollback transaction`
+
+	lock := domain.NewLock(entryForTestDatabase.DbName, "lock_history_test")
+	if _, err := conn.Exec(ctx, "SET application_name = 'lockHolderTest'"); err != nil {
+		t.Fatalf("Error when setting application_name: %v", err)
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lock.Key()); err != nil {
+		t.Fatalf("Error when taking the lock: %v", err)
+	}
+
+	t.Run("Locked", func(t *testing.T) {
+		output := runUtility(t, `up `+connectString+parameters+` -locktimeout 2s`)
+		if !isCorrectOrder(output, "The database is locked by another dbupdater", "lockHolderTest", "waiting up to 2s",
+			"the lock has not been released in 2s") || strings.Contains(output, "Migrations started to apply...") {
+			t.Errorf("up must wait for the lock and exit without applying migrations: %s", output)
+		}
+	})
+
+	t.Run("Unlocked", func(t *testing.T) {
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", lock.Key()); err != nil {
+			t.Fatalf("Error when releasing the lock: %v", err)
+		}
+		output := runUtility(t, `up `+connectString+parameters+` -locktimeout 0`)
+		if !strings.Contains(output, "Migrations have been applied.") || strings.Contains(output, "The database is locked") {
+			t.Errorf("up must apply migrations after the lock has been released: %s", output)
+		}
+	})
+
+	t.Run("HeldForRestore", func(t *testing.T) {
+		// While the database is restored from a dump, dbupdater holds the lock in the database 'postgres'
+		entryForRestore := *entryForTestDatabase
+		entryForRestore.DbName = "postgres"
+		connForRestore, err := helper.OpenConnect(ctx, &entryForRestore, false)
+		if err != nil {
+			t.Fatalf("Error when establishing a connection to the database postgres: %v", err)
+		}
+		defer connForRestore.Close(ctx)
+		if _, err := connForRestore.Exec(ctx, "SET application_name = 'restoreHolderTest'"); err != nil {
+			t.Fatalf("Error when setting application_name: %v", err)
+		}
+		if _, err := connForRestore.Exec(ctx, "SELECT pg_advisory_lock($1)", lock.Key()); err != nil {
+			t.Fatalf("Error when taking the lock: %v", err)
+		}
+
+		createFileAndWrite(t, tmpDir+`/v0.0.1/0001.InsertData.sql`, "insert into lockTest values (1);")
+		output := runUtility(t, `up `+connectString+parameters+` -locktimeout 1s`)
+		if !isCorrectOrder(output, "The database is locked by another dbupdater", "restoreHolderTest",
+			"the lock has not been released in 1s") || strings.Contains(output, "Migrations started to apply...") {
+			t.Errorf("up must not change the database while another dbupdater restores it: %s", output)
+		}
+
+		if _, err := connForRestore.Exec(ctx, "SELECT pg_advisory_unlock($1)", lock.Key()); err != nil {
+			t.Fatalf("Error when releasing the lock: %v", err)
+		}
+		output = runUtility(t, `up `+connectString+parameters+` -locktimeout 0`)
+		if !strings.Contains(output, "Migrations have been applied.") {
+			t.Errorf("up must apply migrations after the restore has finished: %s", output)
+		}
+	})
+}
+
+func TestConfigLayers(t *testing.T) {
+	tmpDir := t.TempDir()
+	pathToConfig := tmpDir + "/dbupdater.yaml"
+	createFileAndWrite(t, pathToConfig, fmt.Sprintf("host: %s\nport: %s\ndbname: %s\nusername: %s\npassword: %s\nrollback: transaction\n",
+		entryForTestDatabase.Host, entryForTestDatabase.Port, entryForTestDatabase.DbName,
+		entryForTestDatabase.User, entryForTestDatabase.Password))
+	createFileAndWrite(t, tmpDir+`/migrations/v0.0.0/0001.Clear.sql`, "")
+
+	t.Run("ConfigShow", func(t *testing.T) {
+		output := runUtility(t, `config show -config `+pathToConfig+` -rollback dump -envfile `+tmpDir+`/.env.missing`)
+		if !strings.Contains(output, "error reading the .env file") {
+			t.Errorf("The missing .env file specified in -envfile must be an error: %s", output)
+		}
+
+		createFileAndWrite(t, tmpDir+`/.env`, "DBUPDATER_HISTORY_TABLE=history_from_env_file\n")
+		output = runUtility(t, `config show -config `+pathToConfig+` -rollback dump -envfile `+tmpDir+`/.env`)
+		if !isCorrectOrder(output, "host", entryForTestDatabase.Host, "config file", "password", "********",
+			"historytable", "history_from_env_file", ".env file DBUPDATER_HISTORY_TABLE", "rollback", "dump", "(flag)") {
+			t.Errorf("config show must show the effective settings and their sources: %s", output)
+		}
+		if strings.Contains(output, "password       "+entryForTestDatabase.Password) {
+			t.Errorf("config show must mask the password: %s", output)
+		}
+	})
+
+	t.Run("ConnectionFromConfigFile", func(t *testing.T) {
+		output := runUtility(t, `status -config `+pathToConfig+` -migrations `+tmpDir+`/migrations`)
+		if !strings.Contains(output, "Updates available:") {
+			t.Errorf("The connection parameters must be taken from the config file: %s", output)
+		}
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS profiles_history_test; DROP TABLE IF EXISTS profilesTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/migrations/v0.0.0/0001.CreateTable.sql`, "create table profilesTest ( id int );")
+	pathToConfig := tmpDir + "/dbupdater.yaml"
+	createFileAndWrite(t, pathToConfig, fmt.Sprintf("host: %s\nport: %s\ndbname: %s\nusername: %s\npassword: %s\n"+
+		"migrations: %s\nhistorytable: profiles_history_test\nrollback: transaction\n"+
+		"profiles:\n  dev:\n    rollback: transaction-per-version\n  prod:\n    protected: true\n",
+		entryForTestDatabase.Host, entryForTestDatabase.Port, entryForTestDatabase.DbName,
+		entryForTestDatabase.User, entryForTestDatabase.Password, tmpDir+"/migrations"))
+
+	t.Run("SelectProfile", func(t *testing.T) {
+		output := runUtility(t, `config show -config `+pathToConfig+` -profile dev`)
+		if !isCorrectOrder(output, "rollback", "transaction-per-version", "profile dev", "profile", "dev") {
+			t.Errorf("The settings of the profile must override the common settings: %s", output)
+		}
+
+		output = runUtility(t, `config show -config `+pathToConfig+` -profile qa`)
+		if !strings.Contains(output, "there is no qa profile in the config file") {
+			t.Errorf("An unknown profile must be an error: %s", output)
+		}
+	})
+
+	t.Run("ProtectedWithoutConfirmation", func(t *testing.T) {
+		output := runUtility(t, `up -config `+pathToConfig+` -profile prod`)
+		if !strings.Contains(output, "profile is protected") || strings.Contains(output, "Migrations started to apply...") {
+			t.Errorf("up must not apply migrations to the protected profile without confirmation: %s", output)
+		}
+	})
+
+	t.Run("ProtectedWithYes", func(t *testing.T) {
+		output := runUtility(t, `up -config `+pathToConfig+` -profile prod -yes`)
+		if !strings.Contains(output, "Migrations have been applied.") {
+			t.Errorf("up must apply migrations to the protected profile with -yes: %s", output)
+		}
+	})
+
+	t.Run("RestoreProtectedWithoutConfirmation", func(t *testing.T) {
+		pathToDump := tmpDir + "/prod.dump"
+		createFileAndWrite(t, pathToDump, "")
+		output := runUtility(t, `restore -config `+pathToConfig+` -profile prod -dump `+pathToDump)
+		if !strings.Contains(output, "profile is protected") {
+			t.Errorf("restore must not drop the database of the protected profile without confirmation: %s", output)
+		}
+	})
+}
+
+func TestSavedPlan(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS saved_plan_history_test; DROP TABLE IF EXISTS savedPlanTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	pathToInsert := tmpDir + `/migrations/v0.0.1/0002.InsertData.sql`
+	createFileAndWrite(t, tmpDir+`/migrations/v0.0.1/0001.CreateTable.sql`, "create table savedPlanTest ( id int );")
+	createFileAndWrite(t, pathToInsert, "insert into savedPlanTest values (1);")
+	pathToPlan := tmpDir + "/plan.json"
+	parameters := ` -migrations ` + tmpDir + `/migrations -historytable saved_plan_history_test -rollback transaction`
+
+	t.Run("SavePlan", func(t *testing.T) {
+		output := runUtility(t, `plan `+connectString+parameters+` -out `+pathToPlan)
+		if !strings.Contains(output, "The plan has been saved to "+pathToPlan) {
+			t.Fatalf("plan -out must save the plan: %s", output)
+		}
+		content, err := os.ReadFile(pathToPlan)
+		if err != nil {
+			t.Fatalf("Error when reading the plan: %v", err)
+		}
+		if !isCorrectOrder(string(content), `"is_init_mode": true`, "0001.CreateTable", "0002.InsertData",
+			domain.CalculateChecksum("insert into savedPlanTest values (1);")) {
+			t.Errorf("The plan must contain the migrations with the checksums: %s", content)
+		}
+	})
+
+	t.Run("SavePlanWithDumpUtilities", func(t *testing.T) {
+		pathToDumpPlan := tmpDir + "/dump-plan.json"
+		pathToDumpUtility := tmpDir + "/missing/pg_dump"
+		output := runUtility(t, `plan `+connectString+parameters+` -rollback dump -pgdump `+pathToDumpUtility+` -out `+pathToDumpPlan)
+		if !strings.Contains(output, "explicitly specified pg_dump") || strings.Contains(output, "The plan has been saved to") {
+			t.Errorf("plan -out must use the pg_dump specified in -pgdump as up -plan does: %s", output)
+		}
+		if _, err := os.Stat(pathToDumpPlan); !os.IsNotExist(err) {
+			t.Errorf("The plan must not be saved if the dump utilities are not found: %v", err)
+		}
+	})
+
+	t.Run("ChangedFile", func(t *testing.T) {
+		createFileAndWrite(t, pathToInsert, "\ninsert into savedPlanTest values (2);")
+		output := runUtility(t, `up `+connectString+parameters+` -plan `+pathToPlan)
+		if !strings.Contains(output, "v0.0.1 0002.InsertData has been changed") || strings.Contains(output, "Migrations started to apply...") {
+			t.Errorf("up must refuse to apply the plan if a migration file has been changed: %s", output)
+		}
+		if err := os.WriteFile(pathToInsert, []byte("insert into savedPlanTest values (1);"), 0o600); err != nil {
+			t.Fatalf("Error when writing to file: %v", err)
+		}
+	})
+
+	t.Run("AddedMigration", func(t *testing.T) {
+		createFileAndWrite(t, tmpDir+`/migrations/v0.0.0/0001.Extra.sql`, "select 1;")
+		output := runUtility(t, `up `+connectString+parameters+` -plan `+pathToPlan)
+		if !strings.Contains(output, "v0.0.0 0001.Extra is not in the plan") || strings.Contains(output, "0001.CreateTable is not in the plan") ||
+			strings.Contains(output, "Migrations started to apply...") {
+			t.Errorf("Only the added migration must be reported as a difference from the plan: %s", output)
+		}
+		if err := os.RemoveAll(tmpDir + `/migrations/v0.0.0`); err != nil {
+			t.Fatalf("Error when deleting the directory: %v", err)
+		}
+	})
+
+	t.Run("TargetWithPlan", func(t *testing.T) {
+		output := runUtility(t, `up `+connectString+parameters+` -plan `+pathToPlan+` -versiondb v0.0.1`)
+		if !strings.Contains(output, "-versiondb and -migration cannot be specified with it") {
+			t.Errorf("up must not accept -versiondb together with -plan: %s", output)
+		}
+	})
+
+	t.Run("ApplyPlan", func(t *testing.T) {
+		output := runUtility(t, `up `+connectString+parameters+` -plan `+pathToPlan)
+		if !isCorrectOrder(output, "Migrations started to apply...", "v0.0.1 0002.InsertData", "Migrations have been applied.") {
+			t.Errorf("up must apply the saved plan: %s", output)
+		}
+	})
+
+	t.Run("ChangedCurrentMigration", func(t *testing.T) {
+		output := runUtility(t, `up `+connectString+parameters+` -plan `+pathToPlan)
+		if !strings.Contains(output, "the current migration of the database is v0.0.1 0002.InsertData") {
+			t.Errorf("up must refuse to apply the plan made for another current migration: %s", output)
+		}
+	})
+}
+
+func TestJsonOutput(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS json_history_test; DROP TABLE IF EXISTS jsonTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTable.sql`, "create table jsonTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0001.BadInsert.sql`, "insert into jsonTest valuez (1);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable json_history_test -rollback transaction-per-version -output json`
+
+	type result struct {
+		Command string `json:"command"`
+		Ok      bool   `json:"ok"`
+		Error   *struct {
+			Kind string `json:"kind"`
+		} `json:"error"`
+		Status *struct {
+			IsInitMode bool `json:"is_init_mode"`
+			Pending    []struct {
+				VersionDb  string   `json:"version_db"`
+				Migrations []string `json:"migrations"`
+			} `json:"pending"`
+		} `json:"status"`
+		Migrations []struct {
+			VersionDb string `json:"version_db"`
+			Name      string `json:"name"`
+			Status    string `json:"status"`
+			Error     *struct {
+				SqlState string `json:"sqlstate"`
+			} `json:"error"`
+		} `json:"migrations"`
+	}
+
+	t.Run("Status", func(t *testing.T) {
+		var status result
+		stdout := runUtilityForJson(t, `status `+connectString+parameters, &status)
+		if status.Command != "status" || !status.Ok || status.Status == nil || !status.Status.IsInitMode ||
+			len(status.Status.Pending) != 2 || status.Status.Pending[1].Migrations[0] != "0001.BadInsert" {
+			t.Errorf("The status must contain the new migrations: %s", stdout)
+		}
+	})
+
+	t.Run("UpWithError", func(t *testing.T) {
+		var up result
+		stdout := runUtilityForJson(t, `up `+connectString+parameters, &up)
+		if up.Ok || up.Error == nil || up.Error.Kind != "apply_failed" || len(up.Migrations) != 2 ||
+			up.Migrations[0].Status != "applied" || up.Migrations[1].Name != "0001.BadInsert" ||
+			up.Migrations[1].Status != "failed" || up.Migrations[1].Error == nil || up.Migrations[1].Error.SqlState != "42601" {
+			t.Errorf("The result must contain the applied version and the failed migration with SQLSTATE: %s", stdout)
+		}
+	})
+
+	t.Run("NoColorsWithoutTerminal", func(t *testing.T) {
+		output := runUtility(t, `status `+connectString+` -migrations `+tmpDir+` -historytable json_history_test`)
+		if strings.Contains(output, "\033[") || !strings.Contains(output, "0001.BadInsert") {
+			t.Errorf("The output must not be colored if stdout is not a terminal: %q", output)
+		}
+	})
+	t.Run("Version", func(t *testing.T) {
+		var version struct {
+			Ok      bool   `json:"ok"`
+			Version string `json:"version"`
+		}
+		stdout := runUtilityForJson(t, `version -output json`, &version)
+		if !version.Ok || version.Version != config.Version {
+			t.Errorf("version must write the version as the JSON document: %s", stdout)
+		}
+
+		if output := runUtility(t, `version`); output != "App version: "+config.Version+"\n" {
+			t.Errorf("version must print the version with the line break: %q", output)
+		}
+	})
+}
+
+func TestExitCodes(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS exit_codes_history_test; DROP TABLE IF EXISTS exitCodesTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTable.sql`, "create table exitCodesTest ( id int );")
+	parameters := ` -migrations ` + tmpDir + ` -historytable exit_codes_history_test -rollback transaction`
+
+	testCases := []struct {
+		name             string
+		parameters       string
+		expectedExitCode int
+	}{
+		{name: "ConfigError", parameters: `up ` + connectString + parameters + ` -rollback never`, expectedExitCode: 2},
+		{name: "UnknownFlag", parameters: `up ` + connectString + parameters + ` -nosuchflag`, expectedExitCode: 2},
+		{name: "Help", parameters: `help up`, expectedExitCode: 0},
+		{name: "FlagHelp", parameters: `status -h`, expectedExitCode: 0},
+		{name: "NoMigrations", parameters: `status ` + connectString, expectedExitCode: 2},
+		{name: "ConnectionError", parameters: `status ` + connectString + parameters + ` -password badPassword`, expectedExitCode: 3},
+		{name: "PendingMigrations", parameters: `status ` + connectString + parameters, expectedExitCode: 4},
+		{name: "Applied", parameters: `up ` + connectString + parameters, expectedExitCode: 0},
+		{name: "UpToDate", parameters: `status ` + connectString + parameters, expectedExitCode: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if exitCode := runUtilityForExitCode(t, tc.parameters); exitCode != tc.expectedExitCode {
+				t.Errorf("Expected exit code %d, received %d", tc.expectedExitCode, exitCode)
+			}
+		})
+	}
+
+	t.Run("ApplyFailed", func(t *testing.T) {
+		createFileAndWrite(t, tmpDir+`/v0.0.2/0001.BadInsert.sql`, "insert into exitCodesTest valuez (1);")
+		if exitCode := runUtilityForExitCode(t, `up `+connectString+parameters); exitCode != 5 {
+			t.Errorf("Expected exit code 5 after the rolled back transaction, received %d", exitCode)
+		}
+	})
+
+	t.Run("PartiallyApplied", func(t *testing.T) {
+		createFileAndWrite(t, tmpDir+`/v0.0.2/0001.BadInsert.sql`, "insert into exitCodesTest values (1);")
+		createFileAndWrite(t, tmpDir+`/v0.0.3/0001.BadInsert.sql`, "insert into exitCodesTest valuez (2);")
+		exitCode := runUtilityForExitCode(t, `up `+connectString+parameters+` -rollback transaction-per-version`)
+		if exitCode != 8 {
+			t.Errorf("Expected exit code 8 when v0.0.2 remains committed, received %d", exitCode)
+		}
+
+		var count int
+		if err := conn.QueryRow(ctx, "SELECT count(*) FROM exitCodesTest").Scan(&count); err != nil {
+			t.Fatalf("Error when reading the test table: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("The row of v0.0.2 must remain, received %d rows", count)
+		}
+	})
+}
+
+func TestStatusCheck(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS check_history_test; DROP TABLE IF EXISTS checkTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTable.sql`, "create table checkTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0001.InsertData.sql`, "insert into checkTest values (1);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable check_history_test -rollback transaction`
+
+	if exitCode := runUtilityForExitCode(t, `status `+connectString+parameters+` -check`); exitCode != 4 {
+		t.Errorf("Without applied migrations the database must be behind, received %d", exitCode)
+	}
+	runUtility(t, `up `+connectString+parameters+` -versiondb v0.0.1`)
+
+	testCases := []struct {
+		name             string
+		check            string
+		expectedExitCode int
+	}{
+		{name: "BehindDirectory", check: ` -check`, expectedExitCode: 4},
+		{name: "RequiredVersion", check: ` -require v0.0.1`, expectedExitCode: 0},
+		{name: "RequiredConstraint", check: ` -require >=v0.0.1,<v0.1`, expectedExitCode: 0},
+		{name: "BehindConstraint", check: ` -require >=v0.0.2`, expectedExitCode: 4},
+		{name: "AheadOfConstraint", check: ` -require <v0.0.1`, expectedExitCode: 7},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if exitCode := runUtilityForExitCode(t, `status `+connectString+parameters+tc.check); exitCode != tc.expectedExitCode {
+				t.Errorf("Expected exit code %d, received %d", tc.expectedExitCode, exitCode)
+			}
+		})
+	}
+
+	t.Run("Match", func(t *testing.T) {
+		runUtility(t, `up `+connectString+parameters)
+		output := runUtility(t, `status `+connectString+parameters+` -check`)
+		if !strings.Contains(output, "Check: match") || strings.Contains(output, "Updates available:") {
+			t.Errorf("After applying all migrations the check must match: %s", output)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		if err := os.RemoveAll(tmpDir + `/v0.0.2`); err != nil {
+			t.Fatalf("Error when deleting the directory: %v", err)
+		}
+		output := runUtility(t, `status `+connectString+parameters+` -check`)
+		if !strings.Contains(output, "Check: ahead_or_unknown, the v0.0.2 0001.InsertData migration is not in the migrations directory") {
+			t.Errorf("The current migration that is not in the directory must be unknown: %s", output)
+		}
+		if exitCode := runUtilityForExitCode(t, `status `+connectString+parameters+` -check`); exitCode != 7 {
+			t.Errorf("Expected exit code 7, received %d", exitCode)
+		}
+	})
+}
+
+func TestScaffold(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS scaffold_history_test;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	parameters := ` -migrations ` + tmpDir
+
+	t.Run("NoVersions", func(t *testing.T) {
+		output := runUtility(t, `new`+parameters+` CreateTable`)
+		if !strings.Contains(output, "create the first one with new-version") {
+			t.Errorf("Without versions the migration must not be created: %s", output)
+		}
+	})
+
+	t.Run("NewVersion", func(t *testing.T) {
+		output := runUtility(t, `new-version`+parameters+` -bump minor`)
+		if !strings.Contains(output, "Created "+filepath.Join(tmpDir, "v0.0.0")) {
+			t.Errorf("In the empty directory the first version v0.0.0 must be created: %s", output)
+		}
+		runUtility(t, `new-version`+parameters)
+		runUtility(t, `new-version`+parameters)
+		runUtility(t, `new-version`+parameters+` -bump minor`)
+		for _, dir := range []string{"v0.0.0", "v0.0.1", "v0.0.2", "v0.1.0"} {
+			if _, err := os.Stat(filepath.Join(tmpDir, dir)); err != nil {
+				t.Errorf("The %s directory has not been created: %v", dir, err)
+			}
+		}
+	})
+
+	t.Run("NewMigration", func(t *testing.T) {
+		runUtility(t, `new`+parameters+` -versiondb v0.0.1 CreateTable -down`)
+		runUtility(t, `new`+parameters+` -versiondb v0.0.1 -notransaction AddIndex`)
+		runUtility(t, `new`+parameters+` InitMinor`)
+		for _, file := range []string{"v0.0.1/0001.CreateTable.sql", "v0.0.1/0001.CreateTable.down.sql",
+			"v0.0.1/0002.AddIndex.sql", "v0.1.0/0001.InitMinor.sql"} {
+			if _, err := os.Stat(filepath.Join(tmpDir, file)); err != nil {
+				t.Errorf("The %s file has not been created: %v", file, err)
+			}
+		}
+
+		output := runUtility(t, `status `+connectString+parameters+` -historytable scaffold_history_test`)
+		if !isCorrectOrder(output, "v0.0.1", "0001.CreateTable", "0002.AddIndex (no transaction)", "v0.1.0", "0001.InitMinor") {
+			t.Errorf("The created migrations must be available for updating: %s", output)
+		}
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		createFileAndWrite(t, tmpDir+`/template.sql`, "-- Migration {{.Name}} of {{.VersionDb}}\n")
+		runUtility(t, `new`+parameters+` -versiondb v0.0.2 -template `+tmpDir+`/template.sql FromTemplate`)
+		content, err := os.ReadFile(filepath.Join(tmpDir, "v0.0.2/0001.FromTemplate.sql"))
+		if err != nil {
+			t.Fatalf("The file has not been created: %v", err)
+		}
+		if string(content) != "-- Migration 0001.FromTemplate of v0.0.2\n" {
+			t.Errorf("The header must be taken from the template: %s", content)
+		}
+	})
+
+	t.Run("WrongName", func(t *testing.T) {
+		if exitCode := runUtilityForExitCode(t, `new`+parameters+` Wrong.Name`); exitCode != 2 {
+			t.Errorf("Expected exit code 2, received %d", exitCode)
+		}
+	})
+}
+
+func TestBaseline(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS baseline_history_test; DROP TABLE IF EXISTS baselineTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	// The database existed before dbupdater, the table of the first migration has already been created
+	if _, err := conn.Exec(ctx, "create table baselineTest ( id int );"); err != nil {
+		t.Fatalf("Error when creating the test table: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTable.sql`, "create table baselineTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0002.AddColumn.sql`, "alter table baselineTest add column name varchar;")
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0001.InsertData.sql`, "insert into baselineTest values (1, 'first');")
+	parameters := ` -migrations ` + tmpDir + ` -historytable baseline_history_test`
+
+	t.Run("WithoutVersion", func(t *testing.T) {
+		if exitCode := runUtilityForExitCode(t, `baseline `+connectString+parameters); exitCode != 2 {
+			t.Errorf("Expected exit code 2, received %d", exitCode)
+		}
+	})
+
+	t.Run("WrongMigration", func(t *testing.T) {
+		output := runUtility(t, `baseline `+connectString+parameters+` -versiondb v0.0.1 -migration 0003.NotExists`)
+		if !strings.Contains(output, "the v0.0.1 0003.NotExists migration is not in -migrations") {
+			t.Errorf("The baseline must be refused for a migration that is not in the directory: %s", output)
+		}
+	})
+
+	t.Run("Baseline", func(t *testing.T) {
+		runUtility(t, `baseline `+connectString+parameters+` -versiondb v0.0.1 -migration 0001.CreateTable`)
+		output := runUtility(t, `status `+connectString+parameters)
+		if !isCorrectOrder(output, "Current database version: v0.0.1", "Last migration applied: 0001.CreateTable",
+			"v0.0.1", "0002.AddColumn", "v0.0.2", "0001.InsertData") {
+			t.Errorf("The migrations after the baseline must be available for updating: %s", output)
+		}
+
+		runUtility(t, `up `+connectString+parameters+` -rollback transaction`)
+		count := 0
+		if err := conn.QueryRow(ctx, "select count(*) from baselineTest where name = 'first'").Scan(&count); err != nil {
+			t.Fatalf("The migrations after the baseline have not been applied: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected 1 row, received %d", count)
+		}
+	})
+
+	t.Run("HistoryIsNotEmpty", func(t *testing.T) {
+		output := runUtility(t, `baseline `+connectString+parameters+` -versiondb v0.0.2`)
+		if !strings.Contains(output, "the baseline is possible only for an empty history") {
+			t.Errorf("The baseline must be refused if there are applied migrations: %s", output)
+		}
+	})
+}
+
+func TestManualChanges(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS manual_history_test; DROP TABLE IF EXISTS manualTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTable.sql`, "create table manualTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0002.InsertData.sql`, "insert into manualTest values (1);")
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0003.BadInsert.sql`, "insert into manualTest valuez (2);")
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0001.InsertMore.sql`, "insert into manualTest values (3);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable manual_history_test -rollback transaction`
+
+	runUtility(t, `up `+connectString+parameters+` -versiondb v0.0.1 -migration 0001.CreateTable`)
+
+	t.Run("WithoutReason", func(t *testing.T) {
+		if exitCode := runUtilityForExitCode(t, `skip `+connectString+parameters); exitCode != 2 {
+			t.Errorf("Expected exit code 2, received %d", exitCode)
+		}
+	})
+
+	t.Run("NotOnDisk", func(t *testing.T) {
+		output := runUtility(t, `repair `+connectString+parameters+` -versiondb v0.0.1 -migration 0009.NotExists -reason INC-0`)
+		if !strings.Contains(output, "the v0.0.1 0009.NotExists migration is not in -migrations") {
+			t.Errorf("The migration that is not in the directory must be refused: %s", output)
+		}
+	})
+
+	t.Run("MarkApplied", func(t *testing.T) {
+		if _, err := conn.Exec(ctx, "insert into manualTest values (1);"); err != nil {
+			t.Fatalf("Error when applying the migration by hand: %v", err)
+		}
+		runUtility(t, `mark-applied `+connectString+parameters+` -versiondb v0.0.1 -migration 0002.InsertData -reason INC-1`)
+		output := runUtility(t, `verify `+connectString+parameters)
+		if !strings.Contains(output, "The files of the applied migrations match the checksums") {
+			t.Errorf("The migration marked as applied must be verified: %s", output)
+		}
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		output := runUtility(t, `skip `+connectString+parameters+` -versiondb v0.0.2 -migration 0001.InsertMore -reason INC-2`)
+		if !strings.Contains(output, "only the next migration v0.0.1 0003.BadInsert can be skipped") {
+			t.Errorf("Only the next migration can be skipped: %s", output)
+		}
+
+		runUtility(t, `skip `+connectString+parameters+` -reason INC-2`)
+		runUtility(t, `up `+connectString+parameters)
+		count := 0
+		if err := conn.QueryRow(ctx, "select count(*) from manualTest").Scan(&count); err != nil {
+			t.Fatalf("Error when reading the test table: %v", err)
+		}
+		if count != 2 {
+			t.Errorf("The migrations after the skipped one must be applied, expected 2 rows, received %d", count)
+		}
+	})
+
+	t.Run("Repair", func(t *testing.T) {
+		runUtility(t, `repair `+connectString+parameters+` -versiondb v0.0.1 -migration 0002.InsertData -reason INC-3`)
+		output := runUtility(t, `status `+connectString+parameters)
+		if !isCorrectOrder(output, "Last migration applied: 0002.InsertData", "0003.BadInsert", "v0.0.2", "0001.InsertMore") {
+			t.Errorf("The repaired migration must be the current one: %s", output)
+		}
+
+		reason, changedBy := "", ""
+		if err := conn.QueryRow(ctx, "select reason, changed_by from manual_history_test where operation = 'repair'").
+			Scan(&reason, &changedBy); err != nil {
+			t.Fatalf("The repair has not been recorded: %v", err)
+		}
+		if reason != "INC-3" || changedBy == "" {
+			t.Errorf("The reason and the user must be recorded, received %q, %q", reason, changedBy)
+		}
+	})
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS history_list_test; DROP TABLE IF EXISTS historyListTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTable.sql`, "create table historyListTest ( id int );")
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0001.InsertData.sql`, "insert into historyListTest values (1);")
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0002.InsertMore.sql`, "insert into historyListTest values (2);")
+	parameters := ` -migrations ` + tmpDir + ` -historytable history_list_test -rollback transaction`
+
+	runUtility(t, `up `+connectString+parameters+` -versiondb v0.0.2 -migration 0001.InsertData`)
+	runUtility(t, `skip `+connectString+parameters+` -reason INC-7`)
+
+	type result struct {
+		Ok      bool `json:"ok"`
+		History []struct {
+			VersionDb   string `json:"version_db"`
+			Name        string `json:"name"`
+			Outcome     string `json:"outcome"`
+			DbUser      string `json:"db_user"`
+			ToolVersion string `json:"tool_version"`
+			Reason      string `json:"reason"`
+		} `json:"history"`
+	}
+
+	t.Run("Table", func(t *testing.T) {
+		output := runUtility(t, `history `+connectString+parameters)
+		if !isCorrectOrder(output, "Time", "Version", "Migration", "Outcome", "Duration", "User", "Client host", "Tool version",
+			"0001.CreateTable", "applied", "0001.InsertData", "applied", "0002.InsertMore", "skip", "INC-7") {
+			t.Errorf("The history must contain all records in the order of recording: %s", output)
+		}
+	})
+
+	t.Run("Json", func(t *testing.T) {
+		var history result
+		stdout := runUtilityForJson(t, `history `+connectString+parameters+` -output json`, &history)
+		if !history.Ok || len(history.History) != 3 || history.History[0].Outcome != "applied" ||
+			history.History[0].DbUser == "" || history.History[0].ToolVersion == "" ||
+			history.History[2].Outcome != "skip" || history.History[2].Reason != "INC-7" {
+			t.Errorf("The history must contain the records with the user, the tool version and the outcome: %s", stdout)
+		}
+	})
+
+	t.Run("FilterByVersion", func(t *testing.T) {
+		var history result
+		stdout := runUtilityForJson(t, `history `+connectString+parameters+` -output json -from v0.0.2 -to v0.0.2`, &history)
+		if len(history.History) != 2 || history.History[0].Name != "0001.InsertData" {
+			t.Errorf("Only the records of v0.0.2 must be shown: %s", stdout)
+		}
+	})
+
+	t.Run("FilterByDate", func(t *testing.T) {
+		tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+		output := runUtility(t, `history `+connectString+parameters+` -since `+tomorrow)
+		if !strings.Contains(output, "No records in the history") {
+			t.Errorf("There must be no records made after today: %s", output)
+		}
+
+		var history result
+		yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+		stdout := runUtilityForJson(t, `history `+connectString+parameters+` -output json -since `+yesterday+` -until `+tomorrow, &history)
+		if len(history.History) != 3 {
+			t.Errorf("All records made today must be shown: %s", stdout)
+		}
+	})
+
+	t.Run("TableOfOlderVersion", func(t *testing.T) {
+		// The table without the columns added in later versions of dbupdater
+		sql := `CREATE TABLE history_list_old_test (id bigserial PRIMARY KEY, version_db varchar NOT NULL, name varchar NOT NULL,
+			operation varchar NOT NULL, checksum varchar, applied_at timestamptz NOT NULL DEFAULT now(), duration_ms bigint,
+			applied_by varchar NOT NULL DEFAULT current_user, tool_version varchar);
+			INSERT INTO history_list_old_test (version_db, name, operation) VALUES ('v0.0.1', '0001.CreateTable', 'import');`
+		if _, err := conn.Exec(ctx, sql); err != nil {
+			t.Fatalf("Error when creating the history table: %v", err)
+		}
+		defer func() {
+			if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS history_list_old_test;"); err != nil {
+				t.Fatalf("Error when deleting test tables: %v", err)
+			}
+		}()
+
+		var history result
+		stdout := runUtilityForJson(t, `history `+connectString+` -migrations `+tmpDir+` -historytable history_list_old_test -output json`, &history)
+		if !history.Ok || len(history.History) != 1 || history.History[0].Outcome != "imported" {
+			t.Errorf("The history must be read from the table without the added columns: %s", stdout)
+		}
+	})
+}
+
+func TestSqlErrorReport(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS sql_error_history_test; DROP TABLE IF EXISTS sql_error_unique_history_test; DROP TABLE IF EXISTS sqlErrorTest;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	syntaxDir := t.TempDir()
+	createFileAndWrite(t, syntaxDir+`/v0.0.1/0001.BadSyntax.sql`,
+		"create table sqlErrorTest ( id int primary key );\n\n-- the second statement\ninsert into sqlErrorTest valuez (1);\nselect 1;\n")
+	syntaxParameters := ` -migrations ` + syntaxDir + ` -historytable sql_error_history_test -rollback transaction-per-version`
+
+	uniqueDir := t.TempDir()
+	createFileAndWrite(t, uniqueDir+`/v0.0.1/0001.Duplicate.sql`,
+		"create table sqlErrorTest ( id int constraint sqlErrorTest_pkey primary key );\ninsert into sqlErrorTest values (1), (1);")
+	uniqueParameters := ` -migrations ` + uniqueDir + ` -historytable sql_error_unique_history_test -rollback transaction-per-version`
+
+	type result struct {
+		Ok         bool `json:"ok"`
+		Migrations []struct {
+			Name  string `json:"name"`
+			Error *struct {
+				SqlState   string `json:"sqlstate"`
+				Detail     string `json:"detail"`
+				Table      string `json:"table"`
+				Constraint string `json:"constraint"`
+				Position   *struct {
+					Line   int `json:"line"`
+					Column int `json:"column"`
+					Lines  []struct {
+						Number int    `json:"number"`
+						Text   string `json:"text"`
+					} `json:"lines"`
+				} `json:"position"`
+			} `json:"error"`
+		} `json:"migrations"`
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		output := runUtility(t, `up `+connectString+syntaxParameters)
+		if !isCorrectOrder(output, "0001.BadSyntax", "SQLSTATE 42601", "line 4, column 26 of v0.0.1 0001.BadSyntax",
+			"2 |", "3 | -- the second statement", "> 4 | insert into sqlErrorTest valuez (1);", "^", "5 | select 1;") {
+			t.Errorf("The error must be shown with SQLSTATE and the lines of the file: %s", output)
+		}
+	})
+
+	t.Run("JsonPosition", func(t *testing.T) {
+		var up result
+		stdout := runUtilityForJson(t, `up `+connectString+syntaxParameters+` -output json`, &up)
+		if up.Ok || len(up.Migrations) != 1 || up.Migrations[0].Error == nil || up.Migrations[0].Error.Position == nil ||
+			up.Migrations[0].Error.Position.Line != 4 || up.Migrations[0].Error.Position.Column != 26 ||
+			len(up.Migrations[0].Error.Position.Lines) != 5 {
+			t.Errorf("The error must contain the line and the column in the file: %s", stdout)
+		}
+	})
+
+	t.Run("JsonConstraint", func(t *testing.T) {
+		var up result
+		stdout := runUtilityForJson(t, `up `+connectString+uniqueParameters+` -output json`, &up)
+		if up.Ok || len(up.Migrations) != 1 || up.Migrations[0].Error == nil || up.Migrations[0].Error.SqlState != "23505" ||
+			up.Migrations[0].Error.Table != "sqlerrortest" || up.Migrations[0].Error.Constraint != "sqlerrortest_pkey" ||
+			up.Migrations[0].Error.Detail == "" {
+			t.Errorf("The error must contain the table and the constraint: %s", stdout)
+		}
+	})
+}
+
+func TestStatements(t *testing.T) {
+	ctx := context.Background()
+	conn, err := helper.OpenConnect(ctx, entryForTestDatabase, false)
+	if err != nil {
+		t.Fatalf("Error when establishing a connection to the database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	defer func() {
+		if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS statements_history_test; DROP TABLE IF EXISTS statementsTest; "+
+			"DROP FUNCTION IF EXISTS statementsTestCount;"); err != nil {
+			t.Fatalf("Error when deleting test tables: %v", err)
+		}
+	}()
+
+	tmpDir := t.TempDir()
+	createFileAndWrite(t, tmpDir+`/v0.0.1/0001.CreateTable.sql`, `-- the table; with a semicolon in the comment
+create table statementsTest ( id int, name text );
+/* the function */
+create function statementsTestCount() returns bigint as $body$
+begin
+	return (select count(*) from statementsTest);
+end;
+$body$ language plpgsql;
+COPY statementsTest (id, name) FROM stdin;
+1	a;b
+2	'c'
+\.
+insert into statementsTest values (3, 'd;e');
+`)
+	createFileAndWrite(t, tmpDir+`/v0.0.2/0001.BadInsert.sql`, "insert into statementsTest values (4, 'f');\n\ninsert into statementsTest valuez (5);\n")
+	parameters := ` -migrations ` + tmpDir + ` -historytable statements_history_test -rollback transaction-per-version -statements`
+
+	type result struct {
+		Ok         bool `json:"ok"`
+		Migrations []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Error  *struct {
+				Statement *struct {
+					Number int    `json:"number"`
+					Count  int    `json:"count"`
+					Line   int    `json:"line"`
+					Text   string `json:"text"`
+				} `json:"statement"`
+				Position *struct {
+					Line int `json:"line"`
+				} `json:"position"`
+			} `json:"error"`
+		} `json:"migrations"`
+	}
+
+	t.Run("Progress", func(t *testing.T) {
+		output := runUtility(t, `up `+connectString+parameters+` -versiondb v0.0.1`)
+		if !isCorrectOrder(output, "statement 1/4 at line 2", "statement 2/4 at line 4", "statement 3/4 at line 9",
+			"statement 4/4 at line 13", "Ready: v0.0.1 0001.CreateTable") {
+			t.Errorf("Every statement must be shown: %s", output)
+		}
+
+		var count int
+		if err := conn.QueryRow(ctx, "select statementsTestCount()").Scan(&count); err != nil {
+			t.Fatalf("Error when counting the rows: %v", err)
+		}
+		if count != 3 {
+			t.Errorf("The rows of COPY and of the insert must be added, got %d rows", count)
+		}
+	})
+
+	t.Run("FailedStatement", func(t *testing.T) {
+		var up result
+		stdout := runUtilityForJson(t, `up `+connectString+parameters+` -output json`, &up)
+		if up.Ok || len(up.Migrations) != 1 || up.Migrations[0].Error == nil || up.Migrations[0].Error.Statement == nil ||
+			up.Migrations[0].Error.Statement.Number != 2 || up.Migrations[0].Error.Statement.Count != 2 ||
+			up.Migrations[0].Error.Statement.Line != 3 || up.Migrations[0].Error.Position == nil ||
+			up.Migrations[0].Error.Position.Line != 3 {
+			t.Errorf("The second statement must be reported as failed: %s", stdout)
+		}
+	})
+}
+
+// ---------------------------------------------------------------FUNCTIONS-------------------------------------------------------------------------------------
+
+// Starts the utility with the passed parameters
+func runUtility(t *testing.T, parameters string) string {
+	t.Helper()
+	params := strings.Split(parameters, " ")
+	cmd := exec.Command(pathToUtility, params...)
+	output, _ := cmd.CombinedOutput()
+
+	return string(output)
+}
+
+// Starts the utility with -output json and decodes the document from stdout. Stdout is returned for the messages about errors.
+func runUtilityForJson(t *testing.T, parameters string, result any) string {
+	t.Helper()
+	params := strings.Split(parameters, " ")
+	cmd := exec.Command(pathToUtility, params...)
+	stdout, _ := cmd.Output()
+
+	if err := json.Unmarshal(stdout, result); err != nil {
+		t.Fatalf("The utility has not written a JSON document: %v: %s", err, stdout)
+	}
+	return string(stdout)
+}
+
+// Starts the utility and returns its exit code
+func runUtilityForExitCode(t *testing.T, parameters string) int {
+	t.Helper()
+	params := strings.Split(parameters, " ")
+	cmd := exec.Command(pathToUtility, params...)
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("Error when starting the utility: %v", err)
+	}
+	return 0
+}
+
+func createFileAndWrite(t *testing.T, path string, content string) {
+	t.Helper()
+	createDir(t, filepath.Dir(path))
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		t.Fatalf("File creation error: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("Error writing to file: %v", err)
+	}
+}
+
+func createDir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0o600); err != nil {
+		t.Fatalf("Error when creating directories for a file: %v", err)
+	}
+}
+
+func removeAll(t *testing.T, path string) {
+	t.Helper()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("Deletion error: %v", err)
+	}
+}
+
+// Checks that all strings are in the string and that they follow each other
+func isCorrectOrder(input string, args ...string) bool {
+	lastIndex := -1
+	for _, str := range args {
+		newIndex := strings.Index(input, str)
+		if newIndex == -1 || newIndex <= lastIndex {
+			return false
+		}
+		lastIndex = newIndex
+	}
+	return true
+}
+
+func isNotContainsAll(input string, args ...string) bool {
+	for _, str := range args {
+		isContains := strings.Contains(input, str)
+		if isContains {
+			return false
+		}
+	}
+	return true
+}
+
+func createZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("File creation error: %v", err)
+	}
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	for name, content := range files {
+		w, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatalf("Error when adding a file to the archive: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Error when writing to the archive: %v", err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatalf("Error when closing the archive: %v", err)
+	}
+}
+
+func createTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("File creation error: %v", err)
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatalf("Error when adding a file to the archive: %v", err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatalf("Error when writing to the archive: %v", err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatalf("Error when closing the archive: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatalf("Error when closing the archive: %v", err)
 	}
-	return true
 }
@@ -1,19 +1,23 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"os"
 
-	"dbupdater/config"
-	"dbupdater/internal/core"
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/internal/core"
 )
 
 func main() {
 	cfg, err := config.NewConfig()
+	if errors.Is(err, config.ErrHelp) {
+		os.Exit(core.ExitOK)
+	}
 	if err != nil {
-		log.Fatalf("Config error: %s", err)
+		log.Printf("Config error: %s", err)
+		os.Exit(core.ExitConfigError)
 	}
 
-	core.Run(cfg)
-	os.Exit(0)
+	os.Exit(core.Run(cfg))
 }
@@ -0,0 +1,750 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Commands are specified before the flags. Example: dbupdater up -versiondb v0.0.2
+const (
+	// Show the current migration and the migrations available for updating
+	CommandStatus = "status"
+
+	// Show the migrations that up would apply, the database is not changed
+	CommandPlan = "plan"
+
+	// Apply the migrations up to the one specified in -versiondb and -migration
+	CommandUp = "up"
+
+	// Compare the files of applied migrations with the checksums recorded when applying
+	CommandVerify = "verify"
+
+	// Execute the down scripts from the current migration to the one specified in -versiondb and -migration
+	CommandRollback = "rollback"
+
+	// Restore the database from the dump left after a failed restore
+	CommandRestore = "restore"
+
+	// Import the current migration from utils/GetCurrentVersion.sql into the empty history table
+	CommandImportLegacy = "import-legacy"
+
+	// Show the applied and reverted migrations and the manual changes with the time, the duration and the user
+	CommandHistory = "history"
+
+	// Record the migration specified in -versiondb and -migration as the current one without executing anything
+	CommandBaseline = "baseline"
+
+	// Record the migrations up to the one specified in -versiondb and -migration as applied without executing them
+	CommandMarkApplied = "mark-applied"
+
+	// Record the next migration as skipped, it is never executed
+	CommandSkip = "skip"
+
+	// Set the migration specified in -versiondb and -migration as the current one without executing anything
+	CommandRepair = "repair"
+
+	// Create the next migration file in the database version, the database is not used
+	CommandNew = "new"
+
+	// Create the directory of the next database version, the database is not used
+	CommandNewVersion = "new-version"
+
+	// Print the effective settings and where they were taken from, the database is not used
+	CommandConfigShow = "config show"
+
+	// Print the dbupdater version
+	CommandVersion = "version"
+)
+
+const nameApp = "dbupdater"
+
+// ErrHelp is returned when the help has been printed at the user's request. It is not an error, dbupdater exits with 0.
+var ErrHelp = errors.New("help requested")
+
+// command describes the help text and the flags of one command
+type command struct {
+	name  string
+	usage string
+
+	// One line in the list of commands
+	summary string
+
+	// Detailed description printed in the help of the command
+	description string
+
+	flagGroups []func(fs *flag.FlagSet, v *flagValues)
+
+	// The name of the positional argument, empty if the command has none
+	argName string
+}
+
+var commands = []command{
+	{
+		name:    CommandStatus,
+		usage:   "[flags]",
+		summary: "show the current migration and the migrations available for updating",
+		description: "Shows the current database version, the last applied migration and the migrations that are not applied yet. " +
+			"The database is not changed. With -check only compares the current migration with the expected one " +
+			"and exits with 0 if they match, 4 if the database is behind, 7 if it is ahead or the state is unknown.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addCheckFlags},
+	},
+	{
+		name:    CommandPlan,
+		usage:   "[flags]",
+		summary: "show the migrations that up would apply",
+		description: "Determines the migrations up to the one specified in -versiondb and -migration in the same way as up " +
+			"and shows them in the order of applying together with the rollback strategy, the dumps that would be created " +
+			"and the current migration after applying. Exits with an error if up would refuse to apply them. " +
+			"The database is not changed and no dump is created.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addTargetToMigrateFlags,
+			addRollbackStrategyFlags, addDumpUtilitiesFlags, addChecksumsFlags, addPlanFlags},
+	},
+	{
+		name:    CommandUp,
+		usage:   "[flags]",
+		summary: "apply new migrations",
+		description: "Applies the migrations up to the one specified in -versiondb and -migration. " +
+			"If neither is specified, all new migrations are applied. With -plan the migrations of the saved plan are applied.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addTargetToMigrateFlags,
+			addRollbackStrategyFlags, addDumpUtilitiesFlags, addChecksumsFlags, addLockFlags, addConfirmFlags, addSavedPlanFlags,
+			addStatementsFlags},
+	},
+	{
+		name:    CommandVerify,
+		usage:   "[flags]",
+		summary: "check that the files of applied migrations have not been changed",
+		description: "Compares the files of the applied migrations with the checksums recorded in the history table when applying. " +
+			"Exits with an error if a file has been changed or deleted.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags},
+	},
+	{
+		name:    CommandRollback,
+		usage:   "-versiondb <version> [-migration <migration>] [flags]",
+		summary: "revert migrations with their down scripts",
+		description: "Executes the down scripts from the current migration to the one specified in -versiondb and -migration, " +
+			"the specified migration remains applied.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addTargetToRollbackFlags,
+			addRollbackStrategyFlags, addDumpUtilitiesFlags, addLockFlags, addConfirmFlags},
+	},
+	{
+		name:    CommandRestore,
+		usage:   "-dump <path> [flags]",
+		summary: "restore the database from a dump",
+		description: "Restores the database from the dump, for example the one left after a failed automatic restore. " +
+			"There must be no active connections to the database.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addDumpUtilitiesFlags, addRestoreFlags, addConfirmFlags},
+	},
+	{
+		name:    CommandImportLegacy,
+		usage:   "[flags]",
+		summary: "import the current migration from utils/GetCurrentVersion.sql into the history table",
+		description: "Imports the current database version and the last applied migration from utils/GetCurrentVersion.sql " +
+			"into the empty history table.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addLockFlags},
+	},
+	{
+		name:    CommandHistory,
+		usage:   "[flags]",
+		summary: "show when the migrations were applied and by whom",
+		description: "Shows the records of the history table in the order of recording: the applied and reverted migrations, " +
+			"the baseline and the manual changes with the time, the duration, the database user, the client host, " +
+			"the dbupdater version and the outcome. The records can be filtered by the versions in -from and -to " +
+			"and by the time in -since and -until. The database is not changed.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addHistoryFlags},
+	},
+	{
+		name:    CommandBaseline,
+		usage:   "-versiondb <version> [-migration <migration>] [flags]",
+		summary: "record the current migration of a database that existed before dbupdater",
+		description: "Creates the history table and records the migration specified in -versiondb and -migration as the current one, " +
+			"nothing is executed. The migration and the migrations before it are considered applied, up applies the migrations after it. " +
+			"The history must be empty.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addTargetToBaselineFlags,
+			addLockFlags, addConfirmFlags},
+	},
+	{
+		name:    CommandMarkApplied,
+		usage:   "-versiondb <version> [-migration <migration>] -reason <reason> [flags]",
+		summary: "record migrations applied by hand as applied",
+		description: "Records the migrations from the current one to the one specified in -versiondb and -migration as applied, " +
+			"nothing is executed. The checksums of their files are recorded and checked by verify. " +
+			"The reason and the user are recorded in the history.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addTargetToMigrateFlags,
+			addReasonFlags, addLockFlags, addConfirmFlags},
+	},
+	{
+		name:    CommandSkip,
+		usage:   "[-versiondb <version> -migration <migration>] -reason <reason> [flags]",
+		summary: "record the next migration as skipped",
+		description: "Records the next migration as skipped, it is not executed and up applies the migrations after it. " +
+			"If -versiondb or -migration is specified, it must be the next migration. " +
+			"The reason and the user are recorded in the history.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addTargetToSkipFlags,
+			addReasonFlags, addLockFlags, addConfirmFlags},
+	},
+	{
+		name:    CommandRepair,
+		usage:   "-versiondb <version> [-migration <migration>] -reason <reason> [flags]",
+		summary: "set the current migration after the database has been fixed by hand",
+		description: "Records the migration specified in -versiondb and -migration as the current one, nothing is executed. " +
+			"It can be before or after the recorded current migration, which may be wrong or unreadable, " +
+			"for example after a failed restore. The reason and the user are recorded in the history.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addTargetToBaselineFlags,
+			addReasonFlags, addLockFlags, addConfirmFlags},
+	},
+	{
+		name:    CommandNew,
+		usage:   "[flags] <name>",
+		summary: "create the next migration file",
+		description: "Creates the migration file with the number following the last migration of the version in -versiondb, " +
+			"for example v0.0.4/0006.AddIndexOnUsers.sql for 'new -versiondb v0.0.4 AddIndexOnUsers'. " +
+			"The file starts with the header from -template. -migrations must be a directory. The database is not used.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addMigrationsDirFlags, addNewMigrationFlags, addTemplateFlags},
+		argName:    "name",
+	},
+	{
+		name:    CommandNewVersion,
+		usage:   "[flags]",
+		summary: "create the directory of the next database version",
+		description: "Creates the directory of the version following the latest version in -migrations, " +
+			"for example v0.0.5, v0.1.0 or v1.0.0 after v0.0.4 with -bump patch, minor or major. " +
+			"If there are no versions, the first version v0.0.0 is created. -migrations must be a directory. The database is not used.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addMigrationsDirFlags, addNewVersionFlags},
+	},
+	{
+		name:    CommandConfigShow,
+		usage:   "[flags]",
+		summary: "show the effective settings with secrets masked",
+		description: "Shows the settings resolved from the config file, the .env file, environment variables and flags, " +
+			"and where each value was taken from. Secrets are masked.",
+		flagGroups: []func(fs *flag.FlagSet, v *flagValues){addConnectionFlags, addMigrationsFlags, addRollbackStrategyFlags,
+			addDumpUtilitiesFlags, addLockFlags},
+	},
+	{
+		name:        CommandVersion,
+		usage:       "[flags]",
+		summary:     "print the dbupdater version",
+		description: "Prints the dbupdater version. With -output json prints it as the JSON document.",
+	},
+}
+
+// Values of all flags. Flags that the command does not have keep the default values.
+type flagValues struct {
+	isVersion    bool
+	isVerbose    bool
+	outputFormat string
+
+	connString string
+	host       string
+	port       string
+	dbname     string
+	user       string
+	password   string
+	service    string
+
+	sslMode     string
+	sslRootCert string
+	sslCert     string
+	sslKey      string
+
+	pathToMigrations string
+	trackingMode     string
+	historyTable     string
+
+	stringVersionDb     string
+	stringNameMigration string
+
+	rollbackStrategy string
+
+	pathToDumpUtility    string
+	pathToRestoreUtility string
+
+	isIgnoreChecksums bool
+	isByStatements    bool
+	isShowSql         bool
+	isCheck           bool
+	requiredVersion   string
+	isImportLegacy    bool
+
+	pathToDump string
+
+	reason string
+
+	historyFrom  string
+	historyTo    string
+	historySince string
+	historyUntil string
+
+	newMigrationName string
+	isCreateDown     bool
+	isNoTransaction  bool
+	pathToTemplate   string
+	bump             string
+
+	pathToPlanOut string
+	pathToPlan    string
+
+	lockTimeout time.Duration
+
+	pathToConfig  string
+	pathToEnvFile string
+	profile       string
+
+	// Set by the profile in the config file
+	isProtected bool
+	isYes       bool
+
+	// Where the value of the setting was taken from, if not from the default value. The key is the flag name.
+	sources map[string]string
+}
+
+func newFlagValues() *flagValues {
+	return &flagValues{
+		trackingMode:     TrackingAuto,
+		historyTable:     DefaultHistoryTable,
+		rollbackStrategy: RollbackDump,
+		outputFormat:     OutputText,
+		bump:             BumpPatch,
+		lockTimeout:      DefaultLockTimeout,
+		pathToEnvFile:    defaultPathToEnvFile,
+		sources:          make(map[string]string),
+	}
+}
+
+// The first argument is the command. Without a command, all flags are accepted and the command is determined
+// as before the commands appeared: status, or up if -versiondb or -migration is specified.
+// The flags are parsed on top of the values from the config file, the .env file and environment variables.
+func parseCmdParameters(args []string) (*Parameters, *DbEntry, *DumpUtilities, []Setting, error) {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return nil, nil, nil, nil, fmt.Errorf("specify a command. Run '%s help' for usage", nameApp)
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			if cmd, _, ok := findCommand(args[1:]); ok {
+				fs := newFlagSet(cmd, newFlagValues())
+				fs.SetOutput(os.Stdout)
+				fs.Usage()
+				return nil, nil, nil, nil, ErrHelp
+			}
+		}
+		printUsage(os.Stdout)
+		return nil, nil, nil, nil, ErrHelp
+	}
+
+	v := newFlagValues()
+	commandName := ""
+	newCommandFlagSet := newFlagSetWithoutCommand
+	flagArgs := args
+	if cmd, cmdFlagArgs, ok := findCommand(args); ok {
+		newCommandFlagSet = func(v *flagValues) *flag.FlagSet {
+			return newFlagSet(cmd, v)
+		}
+		flagArgs = cmdFlagArgs
+		fs := newCommandFlagSet(v)
+		args, err := parseInterspersed(fs, flagArgs)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		switch {
+		case cmd.argName == "" && len(args) != 0:
+			return nil, nil, nil, nil, fmt.Errorf("unexpected arguments for %s: %v", cmd.name, args)
+		case cmd.argName != "" && len(args) != 1:
+			return nil, nil, nil, nil, fmt.Errorf("specify one %s: %s %s %s", cmd.argName, nameApp, cmd.name, cmd.usage)
+		case cmd.argName != "":
+			v.newMigrationName = args[0]
+		}
+		commandName = cmd.name
+	} else {
+		fs := newCommandFlagSet(v)
+		if err := parseFlags(fs, flagArgs); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if fs.NArg() != 0 {
+			return nil, nil, nil, nil, fmt.Errorf("unknown command: %s. Run '%s help' for usage", fs.Arg(0), nameApp)
+		}
+		commandName = determinationCommandWithoutCommand(v)
+		if commandName != CommandVersion {
+			fmt.Fprintf(os.Stderr, "WARNING. Running without a command is deprecated, use '%s %s'.\n", nameApp, commandName)
+		}
+		if commandName == CommandStatus && v.stringNameMigration != "" {
+			fmt.Fprintf(os.Stderr, "WARNING. -migration without -versiondb does not apply migrations, use '%s %s'.\n", nameApp, CommandUp)
+		}
+	}
+
+	// The flags have been checked, now they are applied on top of the other sources
+	newMigrationName := v.newMigrationName
+	v, err := newLayeredFlagValues(v.pathToConfig, v.pathToEnvFile, v.profile)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	v.newMigrationName = newMigrationName
+	// The flags have already been parsed without errors
+	fs := newCommandFlagSet(v)
+	parseInterspersed(fs, flagArgs)
+	fs.Visit(func(f *flag.Flag) {
+		v.sources[f.Name] = SourceFlag
+	})
+
+	configParameters := &Parameters{
+		Command:             commandName,
+		IsVersion:           v.isVersion || commandName == CommandVersion,
+		IsVerbose:           v.isVerbose,
+		OutputFormat:        v.outputFormat,
+		PathToMigrations:    v.pathToMigrations,
+		StringVersionDb:     v.stringVersionDb,
+		StringNameMigration: v.stringNameMigration,
+		TrackingMode:        v.trackingMode,
+		HistoryTable:        v.historyTable,
+		RollbackStrategy:    v.rollbackStrategy,
+		IsIgnoreChecksums:   v.isIgnoreChecksums,
+		IsByStatements:      v.isByStatements,
+		IsShowSql:           v.isShowSql,
+		IsCheck:             v.isCheck || v.requiredVersion != "",
+		RequiredVersion:     v.requiredVersion,
+		PathToDump:          v.pathToDump,
+		Reason:              v.reason,
+		HistoryFrom:         v.historyFrom,
+		HistoryTo:           v.historyTo,
+		HistorySince:        v.historySince,
+		HistoryUntil:        v.historyUntil,
+		NewMigrationName:    v.newMigrationName,
+		IsCreateDown:        v.isCreateDown,
+		IsNoTransaction:     v.isNoTransaction,
+		PathToTemplate:      v.pathToTemplate,
+		Bump:                v.bump,
+		PathToPlanOut:       v.pathToPlanOut,
+		PathToPlan:          v.pathToPlan,
+		LockTimeout:         v.lockTimeout,
+		Profile:             v.profile,
+		IsProtected:         v.isProtected,
+		IsYes:               v.isYes,
+	}
+
+	configDbEntry := &DbEntry{
+		ConnString:  v.connString,
+		Host:        v.host,
+		Port:        v.port,
+		DbName:      v.dbname,
+		User:        v.user,
+		Password:    v.password,
+		Service:     v.service,
+		SslMode:     v.sslMode,
+		SslRootCert: v.sslRootCert,
+		SslCert:     v.sslCert,
+		SslKey:      v.sslKey,
+	}
+
+	configDumpUtilities := &DumpUtilities{
+		PathToDumpUtility:    v.pathToDumpUtility,
+		PathToRestoreUtility: v.pathToRestoreUtility,
+	}
+
+	return configParameters, configDbEntry, configDumpUtilities, effectiveSettings(v), nil
+}
+
+// The flag-only invocation that was used before the commands appeared
+func determinationCommandWithoutCommand(v *flagValues) string {
+	switch {
+	case v.isVersion:
+		return CommandVersion
+	case v.isImportLegacy:
+		return CommandImportLegacy
+	case v.stringVersionDb != "":
+		// Without -versiondb the old versions only showed the status, -migration alone did not apply anything
+		return CommandUp
+	}
+	return CommandStatus
+}
+
+// The name of the command can consist of several words, for example "config show". Returns the arguments after the name.
+func findCommand(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+// The flags can be specified before and after the positional arguments. Example: new AddIndexOnUsers -down.
+// Returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	for fs.NArg() != 0 {
+		positional = append(positional, fs.Arg(0))
+		if err := parseFlags(fs, fs.Args()[1:]); err != nil {
+			return nil, err
+		}
+	}
+	return positional, nil
+}
+
+// The flag package has already printed the error and the usage. -h is returned as ErrHelp.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return ErrHelp
+	}
+	return err
+}
+
+func newFlagSet(cmd command, v *flagValues) *flag.FlagSet {
+	fs := flag.NewFlagSet(nameApp+" "+cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s %s\n\n%s\n", nameApp, cmd.name, cmd.usage, cmd.description)
+		if hasFlags(fs) {
+			fmt.Fprintf(fs.Output(), "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+
+	switch {
+	case len(cmd.flagGroups) != 0:
+		addVerboseFlag(fs, v)
+		addOutputFlag(fs, v)
+		addConfigFlags(fs, v)
+	case cmd.name == CommandVersion:
+		// The version can be written as the JSON document
+		addOutputFlag(fs, v)
+	}
+	for _, addFlags := range cmd.flagGroups {
+		addFlags(fs, v)
+	}
+	return fs
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	isAny := false
+	fs.VisitAll(func(*flag.Flag) {
+		isAny = true
+	})
+	return isAny
+}
+
+// Deprecated invocation without a command accepts the flags of status and up
+func newFlagSetWithoutCommand(v *flagValues) *flag.FlagSet {
+	fs := flag.NewFlagSet(nameApp, flag.ContinueOnError)
+	fs.Usage = func() {
+		printUsage(fs.Output())
+	}
+
+	fs.BoolVar(&v.isVersion, "version", v.isVersion, "Print the dbupdater version and exit.")
+	fs.BoolVar(&v.isImportLegacy, "importlegacy", v.isImportLegacy, "The same as the "+CommandImportLegacy+" command.")
+	addVerboseFlag(fs, v)
+	addOutputFlag(fs, v)
+	addConfigFlags(fs, v)
+	addConnectionFlags(fs, v)
+	addMigrationsFlags(fs, v)
+	addTargetToMigrateFlags(fs, v)
+	addRollbackStrategyFlags(fs, v)
+	addDumpUtilitiesFlags(fs, v)
+	addChecksumsFlags(fs, v)
+	addStatementsFlags(fs, v)
+	addLockFlags(fs, v)
+	addConfirmFlags(fs, v)
+	return fs
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "%s is a console utility for updating the database with the help of an ordered set of migrations.\n\n", nameApp)
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", nameApp)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-15s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s help <command>' for the flags of the command.\n", nameApp)
+}
+
+func addVerboseFlag(fs *flag.FlagSet, v *flagValues) {
+	fs.BoolVar(&v.isVerbose, "verbose", v.isVerbose, "Specifies verbose mode. This will cause dbupdater to output "+
+		"detailed object comments and information about creating/deleting the dump file, and progress messages to standard out.")
+}
+
+func addOutputFlag(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.outputFormat, "output", v.outputFormat, "The format of the result: "+OutputText+" or "+OutputJson+". "+
+		"With "+OutputJson+" one JSON document is written to stdout when the command finishes, "+
+		"the messages about the progress are written to stderr.")
+}
+
+func addConfigFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.pathToConfig, "config", v.pathToConfig, "Path to the config file in YAML (.yaml, .yml) or TOML (.toml) format. "+
+		"The keys are the names of the flags, for example host, password, migrations, rollback. Can also be set in the "+
+		envPathToConfig+" environment variable. Environment variables and flags override the values from the file.")
+	fs.StringVar(&v.profile, "profile", v.profile, "The name of the profile in the config file, for example dev or prod. "+
+		"The settings of the profile override the common settings of the file. Can also be set in the "+envProfile+" environment variable.")
+	fs.StringVar(&v.pathToEnvFile, "envfile", v.pathToEnvFile, "Path to the .env file with DBUPDATER_* and PG* variables, "+
+		"for example DBUPDATER_PASSWORD or PGPASSWORD. The variables of the environment override the values from the file.")
+}
+
+func addConnectionFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.connString, "dsn", v.connString, "Connection string: a URL (postgres://user@host1:5432,host2:5432/db?sslmode=verify-full) "+
+		"or libpq keyword/value pairs (host=/var/run/postgresql dbname=db). The other connection flags override its values. "+
+		"The values that are not specified are taken from the PG* environment variables, the password file (PGPASSFILE) "+
+		"and the service file in the same way as psql does.")
+	fs.StringVar(&v.host, "host", v.host, "Specifies the host name of the machine on which the server is running. "+
+		"A value starting with a slash is the directory of the Unix-domain socket. Several hosts are separated by commas, "+
+		"the migrations are applied to the one that accepts writes.")
+	fs.StringVar(&v.port, "port", v.port, "Specifies the port on which the server is listening for connections. "+
+		"Several ports are separated by commas, one for each host.")
+	fs.StringVar(&v.dbname, "dbname", v.dbname, "Specifies the name of the database to which migrations should be applied. "+
+		"If errors occur when applying migrations, there should be no active connections to the database when restoring the database.")
+	fs.StringVar(&v.user, "username", v.user, "User name to connect as. The user must have permission to connect to the database "+
+		"specified by -dbname. If errors occur when applying migrations, the user must have the right to restore the database:\n"+
+		"1. Connecting to the database 'postgres'\n"+
+		"2. Deleting the database that is specified in -dbname\n"+
+		"3. Must be a member of the database owner role that is specified in -dbname")
+	fs.StringVar(&v.password, "password", v.password, "Password to connect to the database.")
+	fs.StringVar(&v.service, "service", v.service, "The name of the service in pg_service.conf (PGSERVICEFILE) with the connection parameters.")
+	fs.StringVar(&v.sslMode, "sslmode", v.sslMode, "disable, allow, prefer, require, verify-ca or verify-full.")
+	fs.StringVar(&v.sslRootCert, "sslrootcert", v.sslRootCert, "Path to the file with the certificate authorities to verify the server certificate.")
+	fs.StringVar(&v.sslCert, "sslcert", v.sslCert, "Path to the client certificate.")
+	fs.StringVar(&v.sslKey, "sslkey", v.sslKey, "Path to the secret key of the client certificate.")
+}
+
+func addMigrationsFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.pathToMigrations, "migrations", v.pathToMigrations, "Path to the directory with migration scripts or to a .zip or .tar.gz archive with them")
+	fs.StringVar(&v.trackingMode, "tracking", v.trackingMode, "How to store the current migration in the database:\n"+
+		TrackingHistory+" - in the history table owned by dbupdater, it is created automatically;\n"+
+		TrackingLegacy+" - with the help of queries from utils/HasCurrentVersion.sql, utils/GetCurrentVersion.sql and utils/UpdateCurrentVersion.sql;\n"+
+		TrackingAuto+" - "+TrackingLegacy+" if there are utils/*.sql files in the directory with migrations, otherwise "+TrackingHistory+".")
+	fs.StringVar(&v.historyTable, "historytable", v.historyTable, "The name of the history table, it can be schema-qualified.")
+}
+
+func addMigrationsDirFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.pathToMigrations, "migrations", v.pathToMigrations, "Path to the directory with migration scripts, "+
+		"the files are created in it.")
+}
+
+func addNewMigrationFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.stringVersionDb, "versiondb", v.stringVersionDb, "The database version to add the migration to. "+
+		"If not specified, the latest version in -migrations.")
+	fs.BoolVar(&v.isCreateDown, "down", v.isCreateDown, "Also create the file of the script that reverts the migration. "+
+		"Example: 0006.AddIndexOnUsers.down.sql")
+	fs.BoolVar(&v.isNoTransaction, "notransaction", v.isNoTransaction, "Start the file with the -- dbupdater:no-transaction directive, "+
+		"the migration will be executed outside of a transaction.")
+}
+
+func addTemplateFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.pathToTemplate, "template", v.pathToTemplate, "Path to the template of the header of new migration files "+
+		"in the text/template format. Available values: {{.VersionDb}}, {{.Name}}, {{.CreatedAt}}, {{.IsDown}}. "+
+		"If not specified, the header contains the version, the name and the creation time.")
+}
+
+func addNewVersionFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.bump, "bump", v.bump, "The part of the latest version to increase: "+BumpPatch+", "+BumpMinor+" or "+BumpMajor+".")
+}
+
+func addTargetToMigrateFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.stringVersionDb, "versiondb", v.stringVersionDb, "To upgrade the database to the specified version. "+
+		"Or to specify the database version when applied with the -migration parameter.")
+	fs.StringVar(&v.stringNameMigration, "migration", v.stringNameMigration, "Migration file name without extension. To update the database to "+
+		"the specified migration within the database version that is specified in the -versiondb parameter. "+
+		"If -versiondb is not specified, it updates to the specified migration in the current version of the database.")
+}
+
+func addTargetToRollbackFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.stringVersionDb, "versiondb", v.stringVersionDb, "To roll back the database to the last migration of the specified version. "+
+		"Or to specify the database version of the migration in the -migration parameter.")
+	fs.StringVar(&v.stringNameMigration, "migration", v.stringNameMigration, "Migration file name without extension. "+
+		"To roll back the database to the specified migration within the database version that is specified in the -versiondb parameter. "+
+		"If -versiondb is not specified, the migration is searched in the current version of the database.")
+}
+
+func addTargetToBaselineFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.stringVersionDb, "versiondb", v.stringVersionDb, "The database version of the baseline migration.")
+	fs.StringVar(&v.stringNameMigration, "migration", v.stringNameMigration, "Migration file name without extension. "+
+		"The migration up to which the database has already been changed. If not specified, the last migration of -versiondb.")
+}
+
+func addTargetToSkipFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.stringVersionDb, "versiondb", v.stringVersionDb, "The database version of the migration to skip. "+
+		"If not specified, the version of the current migration.")
+	fs.StringVar(&v.stringNameMigration, "migration", v.stringNameMigration, "Migration file name without extension. "+
+		"It must be the next migration. If neither -versiondb nor -migration is specified, the next migration is skipped.")
+}
+
+func addHistoryFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.historyFrom, "from", v.historyFrom, "Show the migrations of this database version and newer.")
+	fs.StringVar(&v.historyTo, "to", v.historyTo, "Show the migrations of this database version and older.")
+	fs.StringVar(&v.historySince, "since", v.historySince, "Show the records made at this time and later: "+
+		"a date (2023-05-01) or a time in RFC 3339 (2023-05-01T15:04:05+03:00).")
+	fs.StringVar(&v.historyUntil, "until", v.historyUntil, "Show the records made before this time. "+
+		"A date includes the whole day.")
+}
+
+func addReasonFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.reason, "reason", v.reason, "Why the current migration is changed by hand, for example the number of the incident. "+
+		"It is recorded in the history together with the user.")
+}
+
+func addRollbackStrategyFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.rollbackStrategy, "rollback", v.rollbackStrategy, "How to return the database to its previous state "+
+		"if an error occurs when applying migrations:\n"+
+		RollbackDump+" - a dump is created before applying migrations, the database is restored from it;\n"+
+		RollbackTransaction+" - all migrations and the update of the current migration are executed in one transaction, "+
+		"which is rolled back. Faster and does not require rights to recreate the database;\n"+
+		RollbackTransactionPerVersion+" - each database version is applied in its own transaction, "+
+		"the versions applied before the error are kept.")
+}
+
+func addDumpUtilitiesFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.pathToDumpUtility, "pgdump", v.pathToDumpUtility, "Path to the pg_dump utility. Can also be set in the "+
+		envPathToDumpUtility+" environment variable. If not specified, pg_dump is searched next to dbupdater, in $PATH "+
+		"and in the well-known PostgreSQL installation directories, the version closest to the server version is selected.")
+	fs.StringVar(&v.pathToRestoreUtility, "pgrestore", v.pathToRestoreUtility, "Path to the pg_restore utility. Can also be set in the "+
+		envPathToRestoreUtility+" environment variable. If not specified, it is searched in the same way as pg_dump.")
+}
+
+func addChecksumsFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.BoolVar(&v.isIgnoreChecksums, "ignorechecksums", v.isIgnoreChecksums, "Apply new migrations even if the files of the applied migrations "+
+		"have been changed or deleted after applying.")
+}
+
+func addStatementsFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.BoolVar(&v.isByStatements, "statements", v.isByStatements, "Split every migration into statements and execute them "+
+		"one by one, showing the number of the statement and its duration. The failed statement is reported. "+
+		"Dollar quoting, comments and COPY ... FROM stdin with the rows in the file are supported.")
+}
+
+func addCheckFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.BoolVar(&v.isCheck, "check", v.isCheck, "Compare the current migration with the last migration in -migrations "+
+		"or, if -require is specified, the current version with -require.")
+	fs.StringVar(&v.requiredVersion, "require", v.requiredVersion, "The database version expected by the application: "+
+		"a version (v0.0.3) or a constraint (\">= v0.0.3, < v0.1\"). Implies -check.")
+}
+
+func addPlanFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.BoolVar(&v.isShowSql, "sql", v.isShowSql, "Show the sql text of every migration, not only the names.")
+	fs.StringVar(&v.pathToPlanOut, "out", v.pathToPlanOut, "Save the plan to the JSON file. up -plan applies exactly "+
+		"these migrations and only if the database and the migration files have not changed. "+
+		"The rollback strategy and the dump utilities are not saved, specify the same -rollback, -pgdump and -pgrestore to up.")
+}
+
+func addSavedPlanFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.pathToPlan, "plan", v.pathToPlan, "Apply the plan saved with plan -out instead of determining the migrations. "+
+		"Up refuses to apply it if the current migration of the database or any migration file differs from the plan.")
+}
+
+func addConfirmFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.BoolVar(&v.isYes, "yes", v.isYes, "Do not ask for confirmation when the profile is protected.")
+}
+
+func addRestoreFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.StringVar(&v.pathToDump, "dump", v.pathToDump, "Path to the dump file to restore the database from.")
+}
+
+func addLockFlags(fs *flag.FlagSet, v *flagValues) {
+	fs.DurationVar(&v.lockTimeout, "locktimeout", v.lockTimeout, "How long to wait if another dbupdater is changing the same database, "+
+		"for example 30s or 5m. 0 - exit with an error at once.")
+}
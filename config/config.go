@@ -1,6 +1,11 @@
 package config
 
-import "flag"
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
 
 type (
 	// Config -.
@@ -8,6 +13,10 @@ type (
 		App
 		Parameters
 		DbEntry
+		DumpUtilities
+
+		// The effective settings for CommandConfigShow
+		Settings []Setting
 	}
 
 	// App -.
@@ -17,85 +26,257 @@ type (
 
 	// Parameters -.
 	Parameters struct {
+		// One of the Command* constants
+		Command string
+
 		IsVersion bool
 		IsVerbose bool
 
+		// One of OutputText, OutputJson
+		OutputFormat string
+
 		PathToMigrations string
 
+		// If it is set, migrations are read from it instead of PathToMigrations, for example from files embedded with go:embed
+		MigrationsFS fs.FS
+
 		StringVersionDb     string
 		StringNameMigration string
+
+		// One of TrackingAuto, TrackingHistory, TrackingLegacy
+		TrackingMode string
+		HistoryTable string
+
+		// One of RollbackDump, RollbackTransaction, RollbackTransactionPerVersion
+		RollbackStrategy string
+
+		// Apply migrations even if the files of applied migrations have been changed
+		IsIgnoreChecksums bool
+
+		// CommandUp executes the statements of every migration one by one and shows the progress
+		IsByStatements bool
+
+		// CommandStatus only compares the current migration with the last migration in the directory or with RequiredVersion
+		IsCheck bool
+
+		// The versions expected by the application. Example: >= v0.0.3, < v0.1
+		RequiredVersion string
+
+		// CommandPlan shows the sql text of the migrations, not only their names
+		IsShowSql bool
+
+		// The dump to restore the database from with CommandRestore
+		PathToDump string
+
+		// Why CommandMarkApplied, CommandSkip or CommandRepair changes the current migration
+		Reason string
+
+		// CommandHistory shows the migrations of the versions from HistoryFrom to HistoryTo inclusive
+		HistoryFrom string
+		HistoryTo   string
+
+		// CommandHistory shows the records made from HistorySince to HistoryUntil. Example: 2023-05-01
+		HistorySince string
+		HistoryUntil string
+
+		// CommandNew creates the migration with this name, the number is added to it. Example: AddIndexOnUsers
+		NewMigrationName string
+
+		// CommandNew also creates the down script
+		IsCreateDown bool
+
+		// CommandNew starts the file with the no-transaction directive
+		IsNoTransaction bool
+
+		// The template of the header of the files created by CommandNew
+		PathToTemplate string
+
+		// The part of the version increased by CommandNewVersion. One of BumpPatch, BumpMinor, BumpMajor
+		Bump string
+
+		// CommandPlan saves the plan to this file
+		PathToPlanOut string
+
+		// CommandUp applies the plan saved in this file instead of determining the migrations
+		PathToPlan string
+
+		// How long to wait for the lock held by another dbupdater. 0 - do not wait.
+		LockTimeout time.Duration
+
+		// The profile selected in the config file
+		Profile string
+
+		// The profile is protected, up and rollback must be confirmed
+		IsProtected bool
+
+		// Confirmation is given in advance
+		IsYes bool
 	}
 
-	// DbEntry -.
+	// DbEntry -. The empty values are taken from ConnString, then in the same way as libpq does:
+	// from PG* environment variables, the password file (PGPASSFILE) and the service file (pg_service.conf).
 	DbEntry struct {
+		// A postgres:// URL or libpq keyword/value pairs. The other fields override its values.
+		ConnString string
+
+		// Several hosts and ports are separated by commas, the primary server is selected.
+		// A host starting with a slash is the directory of the Unix-domain socket.
 		Host     string
 		Port     string
 		DbName   string
 		User     string
 		Password string
+
+		// The name of the service in pg_service.conf
+		Service string
+
+		SslMode     string
+		SslRootCert string
+		SslCert     string
+		SslKey      string
+	}
+
+	// DumpUtilities -. Empty paths mean that the utilities are searched automatically.
+	DumpUtilities struct {
+		PathToDumpUtility    string
+		PathToRestoreUtility string
 	}
 )
 
+// Ways to store the current migration in the database
+const (
+	// TrackingLegacy if there are utils/*.sql files in the directory with migrations, otherwise TrackingHistory
+	TrackingAuto = "auto"
+
+	// The history table owned by dbupdater
+	TrackingHistory = "history"
+
+	// User queries from utils/HasCurrentVersion.sql, utils/GetCurrentVersion.sql, utils/UpdateCurrentVersion.sql
+	TrackingLegacy = "legacy"
+)
+
+// Ways to return the database to its previous state if an error occurs when applying migrations
+const (
+	// A dump is created before applying, the database is restored from it
+	RollbackDump = "dump"
+
+	// All migrations and the update of the current migration are executed in one transaction
+	RollbackTransaction = "transaction"
+
+	// Each database version is applied in its own transaction, the applied versions are kept
+	RollbackTransactionPerVersion = "transaction-per-version"
+)
+
+// Formats of the result of the command
+const (
+	// The messages for the user, colored if stdout is a terminal and NO_COLOR is not set
+	OutputText = "text"
+
+	// One JSON document on stdout when the command finishes, the messages about the progress are written to stderr
+	OutputJson = "json"
+)
+
+// Parts of the latest version that CommandNewVersion increases
+const (
+	// Example: v0.0.4 -> v0.0.5
+	BumpPatch = "patch"
+
+	// Example: v0.0.4 -> v0.1.0
+	BumpMinor = "minor"
+
+	// Example: v0.0.4 -> v1.0.0
+	BumpMajor = "major"
+)
+
+const DefaultHistoryTable = "dbupdater_history"
+
+const DefaultLockTimeout = time.Minute
+
+// The version of dbupdater, it is recorded in the history table together with every applied migration
+const Version = "1.0.0"
+
+const (
+	envPathToDumpUtility    = "DBUPDATER_PG_DUMP"
+	envPathToRestoreUtility = "DBUPDATER_PG_RESTORE"
+)
+
 // NewConfig returns app config.
 func NewConfig() (*Config, error) {
-	const versionApp = "1.0.0"
 	configApp := &App{
-		Version: versionApp,
+		Version: Version,
 	}
 
-	configParameters, configDbEntry := parseCmdParameters()
+	configParameters, configDbEntry, configDumpUtilities, settings, err := parseCmdParameters(os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckParameters(configParameters); err != nil {
+		return nil, err
+	}
 
 	cfg := &Config{
-		App:        *configApp,
-		Parameters: *configParameters,
-		DbEntry:    *configDbEntry,
+		App:           *configApp,
+		Parameters:    *configParameters,
+		DbEntry:       *configDbEntry,
+		DumpUtilities: *configDumpUtilities,
+		Settings:      settings,
 	}
 
 	return cfg, nil
 }
 
-func parseCmdParameters() (*Parameters, *DbEntry) {
-	isVersion := flag.Bool("version", false, "Print the dbupdater version and exit.")
-	isVerbose := flag.Bool("verbose", false, "Specifies verbose mode. This will cause dbupdater to output "+
-		"detailed object comments and information about creating/deleting the dump file, and progress messages to standard out.")
-
-	host := flag.String("host", "", "Specifies the host name of the machine on which the server is running.")
-	port := flag.String("port", "", "Specifies the port on which the server is listening for connections.")
-	dbname := flag.String("dbname", "", "Specifies the name of the database to which migrations should be applied. "+
-		"If errors occur when applying migrations, there should be no active connections to the database when restoring the database.")
-	user := flag.String("username", "", "User name to connect as. The user must have permission to connect to the database "+
-		"specified by -dbname. If errors occur when applying migrations, the user must have the right to restore the database:\n"+
-		"1. Connecting to the database 'postgres'\n"+
-		"2. Deleting the database that is specified in -dbname\n"+
-		"3. Must be a member of the database owner role that is specified in -dbname")
-	password := flag.String("password", "", "Password to connect to the database.")
-
-	pathToMigrations := flag.String("migrations", "", "Path to the directory with migration scripts")
-
-	stringVersionDb := flag.String("versiondb", "", "To upgrade the database to the specified version. "+
-		"Or to specify the database version when applied with the -migration parameter.")
-
-	stringNameMigration := flag.String("migration", "", "Migration file name without extension. To update the database to "+
-		"the specified migration within the database version that is specified in the -versiondb parameter. "+
-		"If -versiondb is not specified, it updates to the specified migration in the current version of the database.")
+// Checks the values of the parameters that can take only certain values
+func CheckParameters(parameters *Parameters) error {
+	if err := checkTrackingMode(parameters.TrackingMode); err != nil {
+		return err
+	}
+	if err := checkRollbackStrategy(parameters.RollbackStrategy); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(parameters.OutputFormat); err != nil {
+		return err
+	}
+	if err := checkBump(parameters.Bump); err != nil {
+		return err
+	}
+	if parameters.PathToPlan != "" && (parameters.StringVersionDb != "" || parameters.StringNameMigration != "") {
+		return fmt.Errorf("the migrations to apply are taken from -plan, -versiondb and -migration cannot be specified with it")
+	}
+	if parameters.LockTimeout < 0 {
+		return fmt.Errorf("wrong value in -locktimeout: %s, it cannot be negative", parameters.LockTimeout)
+	}
+	return nil
+}
 
-	flag.Parse()
+func checkTrackingMode(trackingMode string) error {
+	switch trackingMode {
+	case TrackingAuto, TrackingHistory, TrackingLegacy:
+		return nil
+	}
+	return fmt.Errorf("wrong value in -tracking: %s, expected %s, %s or %s", trackingMode, TrackingAuto, TrackingHistory, TrackingLegacy)
+}
 
-	configParameters := &Parameters{
-		IsVersion:           *isVersion,
-		IsVerbose:           *isVerbose,
-		PathToMigrations:    *pathToMigrations,
-		StringVersionDb:     *stringVersionDb,
-		StringNameMigration: *stringNameMigration,
+func checkRollbackStrategy(rollbackStrategy string) error {
+	switch rollbackStrategy {
+	case RollbackDump, RollbackTransaction, RollbackTransactionPerVersion:
+		return nil
 	}
+	return fmt.Errorf("wrong value in -rollback: %s, expected %s, %s or %s",
+		rollbackStrategy, RollbackDump, RollbackTransaction, RollbackTransactionPerVersion)
+}
 
-	configDbEntry := &DbEntry{
-		Host:     *host,
-		Port:     *port,
-		DbName:   *dbname,
-		User:     *user,
-		Password: *password,
+func checkOutputFormat(outputFormat string) error {
+	switch outputFormat {
+	case OutputText, OutputJson:
+		return nil
 	}
+	return fmt.Errorf("wrong value in -output: %s, expected %s or %s", outputFormat, OutputText, OutputJson)
+}
 
-	return configParameters, configDbEntry
+func checkBump(bump string) error {
+	switch bump {
+	case BumpPatch, BumpMinor, BumpMajor:
+		return nil
+	}
+	return fmt.Errorf("wrong value in -bump: %s, expected %s, %s or %s", bump, BumpPatch, BumpMinor, BumpMajor)
 }
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// The settings are taken in the order of increasing priority: default values, the config file (-config),
+// the .env file (-envfile), environment variables, flags
+const (
+	SourceDefault    = "default"
+	SourceConfigFile = "config file"
+	SourceEnvFile    = ".env file"
+	SourceEnv        = "environment"
+	SourceFlag       = "flag"
+)
+
+const (
+	envPathToConfig = "DBUPDATER_CONFIG"
+	envProfile      = "DBUPDATER_PROFILE"
+
+	defaultPathToEnvFile = ".env"
+)
+
+// setting can be set in the config file with the key equal to the flag name, in the environment variables and with the flag
+type setting struct {
+	flag string
+
+	// In the order of decreasing priority
+	envs []string
+
+	isSecret bool
+}
+
+var settings = []setting{
+	{flag: "dsn", envs: []string{"DBUPDATER_DSN"}, isSecret: true},
+	{flag: "host", envs: []string{"DBUPDATER_HOST", "PGHOST"}},
+	{flag: "port", envs: []string{"DBUPDATER_PORT", "PGPORT"}},
+	{flag: "dbname", envs: []string{"DBUPDATER_DBNAME", "PGDATABASE"}},
+	{flag: "username", envs: []string{"DBUPDATER_USER", "PGUSER"}},
+	{flag: "password", envs: []string{"DBUPDATER_PASSWORD", "PGPASSWORD"}, isSecret: true},
+	{flag: "service", envs: []string{"DBUPDATER_SERVICE", "PGSERVICE"}},
+	{flag: "sslmode", envs: []string{"DBUPDATER_SSLMODE", "PGSSLMODE"}},
+	{flag: "sslrootcert", envs: []string{"DBUPDATER_SSLROOTCERT", "PGSSLROOTCERT"}},
+	{flag: "sslcert", envs: []string{"DBUPDATER_SSLCERT", "PGSSLCERT"}},
+	{flag: "sslkey", envs: []string{"DBUPDATER_SSLKEY", "PGSSLKEY"}},
+	{flag: "migrations", envs: []string{"DBUPDATER_MIGRATIONS"}},
+	{flag: "tracking", envs: []string{"DBUPDATER_TRACKING"}},
+	{flag: "historytable", envs: []string{"DBUPDATER_HISTORY_TABLE"}},
+	{flag: "rollback", envs: []string{"DBUPDATER_ROLLBACK"}},
+	{flag: "pgdump", envs: []string{envPathToDumpUtility}},
+	{flag: "pgrestore", envs: []string{envPathToRestoreUtility}},
+	{flag: "template", envs: []string{"DBUPDATER_TEMPLATE"}},
+	{flag: "statements", envs: []string{"DBUPDATER_STATEMENTS"}},
+	{flag: "locktimeout", envs: []string{"DBUPDATER_LOCK_TIMEOUT"}},
+	{flag: "verbose", envs: []string{"DBUPDATER_VERBOSE"}},
+	{flag: "output", envs: []string{"DBUPDATER_OUTPUT"}},
+}
+
+// Setting is the effective value of a setting and where it was taken from. Secrets are masked.
+type Setting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Keys of the config file that are not flags
+const (
+	// Named sets of settings, one of them is selected with -profile
+	keyProfiles = "profiles"
+
+	// In a profile: the commands that change the database ask for confirmation or require -yes
+	keyProtected = "protected"
+)
+
+// configFile is the content of the config file
+type configFile struct {
+	values map[string]string
+
+	// The settings of the selected profile, they override values
+	profileValues map[string]string
+
+	// Empty if no profile is selected
+	profile     string
+	isProtected bool
+}
+
+// Returns the values of the settings taken from the config file, the .env file and environment variables.
+// The flags are parsed on top of them.
+func newLayeredFlagValues(pathToConfig string, pathToEnvFile string, profile string) (*flagValues, error) {
+	v := newFlagValues()
+	fs := newFlagSetForSettings(v)
+
+	envFile, err := readEnvFile(pathToEnvFile)
+	if err != nil {
+		return nil, err
+	}
+	lookupEnv := func(name string) (string, string, bool) {
+		if value := os.Getenv(name); value != "" {
+			return value, SourceEnv + " " + name, true
+		}
+		if value := envFile[name]; value != "" {
+			return value, SourceEnvFile + " " + name, true
+		}
+		return "", "", false
+	}
+
+	if pathToConfig == "" {
+		pathToConfig, _, _ = lookupEnv(envPathToConfig)
+	}
+	profileSource := SourceFlag
+	if profile == "" {
+		profile, profileSource, _ = lookupEnv(envProfile)
+	}
+	if pathToConfig == "" && profile != "" {
+		return nil, fmt.Errorf("the %s profile is specified, but the config file is not specified in -config", profile)
+	}
+	if pathToConfig != "" {
+		cfgFile, err := readConfigFile(pathToConfig, profile)
+		if err != nil {
+			return nil, err
+		}
+		source := SourceConfigFile + " " + pathToConfig
+		for _, key := range sortedKeys(cfgFile.values) {
+			if err := fs.Set(key, cfgFile.values[key]); err != nil {
+				return nil, fmt.Errorf("wrong value of %s in the config file %s: %w", key, pathToConfig, err)
+			}
+			v.sources[key] = source
+		}
+		for _, key := range sortedKeys(cfgFile.profileValues) {
+			if err := fs.Set(key, cfgFile.profileValues[key]); err != nil {
+				return nil, fmt.Errorf("wrong value of %s in the %s profile of the config file %s: %w", key, profile, pathToConfig, err)
+			}
+			v.sources[key] = source + " profile " + profile
+		}
+		v.profile = cfgFile.profile
+		v.isProtected = cfgFile.isProtected
+		v.sources["profile"] = profileSource
+	}
+
+	for _, s := range settings {
+		for _, name := range s.envs {
+			value, source, ok := lookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := fs.Set(s.flag, value); err != nil {
+				return nil, fmt.Errorf("wrong value in the %s environment variable: %w", name, err)
+			}
+			v.sources[s.flag] = source
+			break
+		}
+	}
+	return v, nil
+}
+
+// The flag set is used only to convert the values from the strings in the same way as the flags do
+func newFlagSetForSettings(v *flagValues) *flag.FlagSet {
+	fs := flag.NewFlagSet(nameApp, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addVerboseFlag(fs, v)
+	addOutputFlag(fs, v)
+	addConnectionFlags(fs, v)
+	addMigrationsFlags(fs, v)
+	addRollbackStrategyFlags(fs, v)
+	addDumpUtilitiesFlags(fs, v)
+	addTemplateFlags(fs, v)
+	addStatementsFlags(fs, v)
+	addLockFlags(fs, v)
+	return fs
+}
+
+// Returns the effective settings in the order of the settings table
+func effectiveSettings(v *flagValues) []Setting {
+	fs := newFlagSetForSettings(v)
+	result := make([]Setting, 0, len(settings))
+	for _, s := range settings {
+		value := fs.Lookup(s.flag).Value.String()
+		if s.isSecret && value != "" {
+			value = "********"
+		}
+		source, ok := v.sources[s.flag]
+		if !ok {
+			source = SourceDefault
+		}
+		result = append(result, Setting{Name: s.flag, Value: value, Source: source})
+	}
+
+	if v.profile != "" {
+		value := v.profile
+		if v.isProtected {
+			value += " (protected)"
+		}
+		result = append(result, Setting{Name: "profile", Value: value, Source: v.sources["profile"]})
+	}
+	return result
+}
+
+// The format is determined by the extension: .yaml, .yml or .toml. The keys are the names of the flags.
+// The settings of the profile override the common settings of the file.
+func readConfigFile(pathToConfig string, profile string) (*configFile, error) {
+	content, err := os.ReadFile(pathToConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the config file: %w", err)
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(pathToConfig)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	default:
+		return nil, fmt.Errorf("unknown format of the config file %s, expected .yaml, .yml or .toml", pathToConfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the config file %s: %w", pathToConfig, err)
+	}
+
+	profiles, ok := raw[keyProfiles].(map[string]any)
+	if _, isExists := raw[keyProfiles]; isExists && !ok {
+		return nil, fmt.Errorf("%s in the config file %s must contain named sets of settings", keyProfiles, pathToConfig)
+	}
+	delete(raw, keyProfiles)
+
+	cfgFile := &configFile{
+		values:        make(map[string]string),
+		profileValues: make(map[string]string),
+	}
+	if err := addConfigValues(cfgFile.values, raw); err != nil {
+		return nil, fmt.Errorf("%w in the config file %s", err, pathToConfig)
+	}
+	if profile == "" {
+		return cfgFile, nil
+	}
+
+	rawProfile, ok := profiles[profile].(map[string]any)
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("there is no %s profile in the config file %s, the profiles: %s",
+			profile, pathToConfig, strings.Join(names, ", "))
+	}
+	if protected, isExists := rawProfile[keyProtected]; isExists {
+		isProtected, ok := protected.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s of the %s profile in the config file %s must be true or false", keyProtected, profile, pathToConfig)
+		}
+		cfgFile.isProtected = isProtected
+		delete(rawProfile, keyProtected)
+	}
+	if err := addConfigValues(cfgFile.profileValues, rawProfile); err != nil {
+		return nil, fmt.Errorf("%w in the %s profile of the config file %s", err, profile, pathToConfig)
+	}
+	cfgFile.profile = profile
+	return cfgFile, nil
+}
+
+func addConfigValues(values map[string]string, raw map[string]any) error {
+	for key, value := range raw {
+		if !isSetting(key) {
+			return fmt.Errorf("unknown setting %s", key)
+		}
+		values[key] = fmt.Sprint(value)
+	}
+	return nil
+}
+
+// The default .env file may be absent
+func readEnvFile(pathToEnvFile string) (map[string]string, error) {
+	envFile, err := godotenv.Read(pathToEnvFile)
+	if errors.Is(err, fs.ErrNotExist) && pathToEnvFile == defaultPathToEnvFile {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the .env file %s: %w", pathToEnvFile, err)
+	}
+	return envFile, nil
+}
+
+func isSetting(name string) bool {
+	for _, s := range settings {
+		if s.flag == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
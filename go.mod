@@ -1,11 +1,13 @@
-module dbupdater
+module github.com/awsoremod/DBUpdater
 
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/hashicorp/go-version v1.6.0
 	github.com/jackc/pgx/v5 v5.4.1
 	github.com/joho/godotenv v1.5.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"os/user"
 	"strings"
 
-	"dbupdater/config"
+	"github.com/awsoremod/DBUpdater/config"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -23,9 +24,10 @@ func ShowIfVerbose(isVerbose bool, str string) {
 func OpenConnect(ctx context.Context, config *config.DbEntry, isVerbose bool) (*pgx.Conn, error) {
 	ShowIfVerbose(isVerbose, "Connecting to the database...")
 
-	connString := fmt.Sprintf(
-		"host=%s port=%s dbname=%s user=%s password=%s target_session_attrs=read-write",
-		config.Host, config.Port, config.DbName, config.User, config.Password)
+	connString, err := ConnString(config)
+	if err != nil {
+		return nil, err
+	}
 
 	connConfig, err := pgx.ParseConfig(connString)
 	if err != nil {
@@ -40,6 +42,11 @@ func OpenConnect(ctx context.Context, config *config.DbEntry, isVerbose bool) (*
 	return conn, nil
 }
 
+// Returns the server version reported by the server when connecting. Example: 15.2 (Debian 15.2-1.pgdg110+1)
+func GetServerVersion(conn *pgx.Conn) string {
+	return conn.PgConn().ParameterStatus("server_version")
+}
+
 // Checks that the string starts with "v"
 func IsFirstV(name string) bool {
 	if name[:len("v")] == "v" {
@@ -48,14 +55,9 @@ func IsFirstV(name string) bool {
 	return false
 }
 
-func GetFilesInDir(pathToDir string) ([]fs.DirEntry, error) {
-	dir, err := os.Open(pathToDir)
-	if err != nil {
-		return nil, err
-	}
-	defer dir.Close()
-
-	files, err := dir.ReadDir(-1)
+// Returns the entries of the directory sorted by name. The path is slash-separated and relative to the root of fsys.
+func GetFilesInDir(fsys fs.FS, pathToDir string) ([]fs.DirEntry, error) {
+	files, err := fs.ReadDir(fsys, pathToDir)
 	if err != nil {
 		return nil, err
 	}
@@ -63,8 +65,9 @@ func GetFilesInDir(pathToDir string) ([]fs.DirEntry, error) {
 	return files, nil
 }
 
-func ReadFile(pathToSqlFile string) (string, error) {
-	file, err := os.ReadFile(pathToSqlFile)
+// The path is slash-separated and relative to the root of fsys
+func ReadFile(fsys fs.FS, pathToSqlFile string) (string, error) {
+	file, err := fs.ReadFile(fsys, pathToSqlFile)
 	if err != nil {
 		return "", err
 	}
@@ -81,3 +84,14 @@ func IndexAt(s, sep string, n int) int {
 	}
 	return idx
 }
+
+// Returns the name of the user of the operating system, empty if it is unknown
+func CurrentOsUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
@@ -0,0 +1,132 @@
+package helper
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Opens the migrations from a directory, a .zip archive or a .tar.gz (.tgz) archive.
+// If the archive contains one directory, for example migrations/v0.0.1/..., the migrations are read from it.
+// The returned function releases the archive and must be called when the migrations are no longer needed.
+func OpenMigrationsFS(pathToMigrations string) (fs.FS, func() error, error) {
+	lowerPath := strings.ToLower(pathToMigrations)
+	switch {
+	case strings.HasSuffix(lowerPath, ".zip"):
+		reader, err := zip.OpenReader(pathToMigrations)
+		if err != nil {
+			return nil, nil, err
+		}
+		fsys, err := rootOfArchive(reader)
+		if err != nil {
+			reader.Close()
+			return nil, nil, err
+		}
+		return fsys, reader.Close, nil
+	case strings.HasSuffix(lowerPath, ".tar.gz"), strings.HasSuffix(lowerPath, ".tgz"):
+		tmpDir, err := os.MkdirTemp("", "dbupdater-migrations-")
+		if err != nil {
+			return nil, nil, err
+		}
+		removeTmpDir := func() error { return os.RemoveAll(tmpDir) }
+		if err := extractTarGz(pathToMigrations, tmpDir); err != nil {
+			removeTmpDir()
+			return nil, nil, err
+		}
+		fsys, err := rootOfArchive(os.DirFS(tmpDir))
+		if err != nil {
+			removeTmpDir()
+			return nil, nil, err
+		}
+		return fsys, removeTmpDir, nil
+	}
+
+	info, err := os.Stat(pathToMigrations)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("%s is not a directory, .zip or .tar.gz archive", pathToMigrations)
+	}
+	return os.DirFS(pathToMigrations), func() error { return nil }, nil
+}
+
+// If the root of the archive contains only one directory and it is not a database version, returns it
+func rootOfArchive(fsys fs.FS) (fs.FS, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() || IsFirstV(entries[0].Name()) {
+		return fsys, nil
+	}
+	return fs.Sub(fsys, entries[0].Name())
+}
+
+// Only directories and regular files are extracted
+func extractTarGz(pathToArchive string, dstDir string) error {
+	file, err := os.Open(pathToArchive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("error when reading %s: %w", pathToArchive, err)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error when reading %s: %w", pathToArchive, err)
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(header.Name, "./"), "/")
+		if name == "" || name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return fmt.Errorf("the archive %s contains an invalid path: %s", pathToArchive, header.Name)
+		}
+		dstPath := filepath.Join(dstDir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dstPath, 0o700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tarReader, dstPath); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractFile(reader io.Reader, dstPath string) error {
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o700); err != nil {
+		return err
+	}
+	dstFile, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, reader); err != nil {
+		dstFile.Close()
+		return err
+	}
+	return dstFile.Close()
+}
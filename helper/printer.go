@@ -0,0 +1,120 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// Logger receives the messages that dbupdater shows to the user. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// Printer shows messages to the user. Detailed messages are shown only in verbose mode.
+type Printer struct {
+	isVerbose bool
+	logger    Logger
+	errLogger Logger
+
+	// If false, the color escape sequences are removed from the messages
+	isColor bool
+
+	// The result of the command in JSON is written to it. Nil if the result is shown as messages.
+	documents io.Writer
+}
+
+// The escape sequences that set the color of the text. Example: \033[32m
+var colorSequence = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// Messages are written to stdout, errors to stderr.
+// The messages are colored only if stdout is a terminal and the NO_COLOR environment variable is not set.
+func NewStdPrinter(isVerbose bool) *Printer {
+	return &Printer{
+		isVerbose: isVerbose,
+		logger:    &writerLogger{w: os.Stdout},
+		errLogger: &writerLogger{w: os.Stderr},
+		isColor:   isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+	}
+}
+
+// The result of the command is written to stdout as one JSON document with PrintDocument,
+// all messages are written to stderr without colors
+func NewJsonPrinter(isVerbose bool) *Printer {
+	return &Printer{
+		isVerbose: isVerbose,
+		logger:    &writerLogger{w: os.Stderr},
+		errLogger: &writerLogger{w: os.Stderr},
+		documents: os.Stdout,
+	}
+}
+
+// All messages, including errors, are written to the logger without colors
+func NewPrinter(logger Logger, isVerbose bool) *Printer {
+	return &Printer{
+		isVerbose: isVerbose,
+		logger:    logger,
+		errLogger: logger,
+	}
+}
+
+func (p *Printer) IsVerbose() bool {
+	return p.isVerbose
+}
+
+// The result of the command is written with PrintDocument
+func (p *Printer) IsJson() bool {
+	return p.documents != nil
+}
+
+func (p *Printer) Printf(format string, v ...any) {
+	p.logger.Printf("%s", p.withoutColors(fmt.Sprintf(format, v...)))
+}
+
+func (p *Printer) Println(v ...any) {
+	p.logger.Printf("%s", p.withoutColors(fmt.Sprintln(v...)))
+}
+
+// Shows the message about an error
+func (p *Printer) Errorf(format string, v ...any) {
+	p.errLogger.Printf("%s", p.withoutColors(fmt.Sprintf(format, v...)))
+}
+
+// Executes Println if verbose mode is on
+func (p *Printer) ShowIfVerbose(str string) {
+	if p.isVerbose {
+		p.Println(str)
+	}
+}
+
+// Writes the result of the command as a JSON document. Does nothing if the result is shown as messages.
+func (p *Printer) PrintDocument(document any) error {
+	if p.documents == nil {
+		return nil
+	}
+	encoder := json.NewEncoder(p.documents)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+func (p *Printer) withoutColors(str string) string {
+	if p.isColor {
+		return str
+	}
+	return colorSequence.ReplaceAllString(str, "")
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+type writerLogger struct {
+	w io.Writer
+}
+
+func (l *writerLogger) Printf(format string, v ...any) {
+	fmt.Fprintf(l.w, format, v...)
+}
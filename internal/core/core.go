@@ -5,179 +5,414 @@ package core
 // The project has a policy that usecase does not depend on usecase, infrastructure does not depend on infrastructure.
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
+	"text/tabwriter"
 
-	"dbupdater/config"
-	"dbupdater/helper"
-	"dbupdater/internal/domain"
-	"dbupdater/internal/usecase"
-
-	"dbupdater/internal/infrastructure/dump_postgres"
-	"dbupdater/internal/infrastructure/repo/migration_disk"
-	"dbupdater/internal/infrastructure/repo/migration_postgres"
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/internal/usecase"
 
 	"github.com/jackc/pgx/v5"
 )
 
-func Run(cfg *config.Config) {
+// Executes the command and returns the exit code
+func Run(cfg *config.Config) int {
+	ctx := context.Background()
+	printer := helper.NewStdPrinter(cfg.IsVerbose)
+	if cfg.OutputFormat == config.OutputJson {
+		printer = helper.NewJsonPrinter(cfg.IsVerbose)
+	}
 	if cfg.Parameters.IsVersion {
-		fmt.Printf("App version: %s", cfg.App.Version)
-		return
+		printer.Printf("App version: %s\n", cfg.App.Version)
+		return printResult(printer, &document{Command: config.CommandVersion, Ok: true, Version: cfg.App.Version})
 	}
-	checkRequiredParameters(cfg)
-
-	ctx := context.Background()
-	conn, err := helper.OpenConnect(ctx, &cfg.DbEntry, cfg.IsVerbose)
-	if err != nil {
-		log.Fatalf("Error when connecting to the database: %s", err)
+	if cfg.Command == config.CommandConfigShow {
+		showSettings(printer, cfg.Settings)
+		return printResult(printer, &document{Command: cfg.Command, Ok: true, Settings: newSettingDocuments(cfg.Settings)})
+	}
+	switch cfg.Command {
+	case config.CommandNew:
+		paths, err := NewMigration(ctx, cfg, printer)
+		result := &document{Command: cfg.Command, Created: paths}
+		if err != nil {
+			return exitWithError(printer, result, err)
+		}
+		result.Ok = true
+		return printResult(printer, result)
+	case config.CommandNewVersion:
+		path, err := NewVersion(ctx, cfg, printer)
+		if err != nil {
+			return exitWithError(printer, &document{Command: cfg.Command}, err)
+		}
+		return printResult(printer, &document{Command: cfg.Command, Ok: true, Created: []string{path}})
 	}
-	defer conn.Close(ctx)
 
-	repoMigrationPostgres := migration_postgres.NewMigrationPostgresRepo(conn)
-	ucFileReader := usecase.NewFileReaderUseCase(cfg.PathToMigrations, cfg.IsVerbose)
-	ucMigrationCurrent := usecase.NewMigrationCurrentUseCase(repoMigrationPostgres, cfg.IsVerbose)
-	ucInitMigration, err := usecase.NewInitMigrationUseCase()
+	runner, err := NewRunner(ctx, cfg, printer)
 	if err != nil {
-		log.Fatalf("Error when creating ucInitMigration: %s", err)
+		return exitWithError(printer, &document{Command: cfg.Command}, err)
 	}
-
-	isInitModeON := isInitMode(ucFileReader, ucMigrationCurrent)
-	if isInitModeON {
-		fmt.Printf("Initialization mode - ON\n"+
-			"Migrations will be applied starting from version %s\n", ucInitMigration.GetInitMigration().VersionDb.String())
+	runner.HandleInterrupt()
+	if cfg.IsProtected && !cfg.IsYes {
+		runner.SetConfirmation(confirmInTerminal(printer, cfg.Profile, runner.cfg.DbName))
 	}
 
-	currentMigration := determinationCurrentMigration(isInitModeON, ucInitMigration, ucFileReader, ucMigrationCurrent)
-	showCurrentMigration(currentMigration)
-
-	repoMigrationDisk := migration_disk.NewMigrationDiskRepoo(cfg.PathToMigrations, cfg.IsVerbose)
-	ucMigrations := usecase.NewMigrationsUseCase(repoMigrationDisk, cfg.IsVerbose)
-
-	unappliedMigrations, err := ucMigrations.GetUnappliedSortedMigrations(ctx, isInitModeON, currentMigration)
+	result, err := runCommand(ctx, runner, cfg, printer)
+	runner.Close(ctx)
 	if err != nil {
-		log.Fatalf("Error when receiving unapplied migrations: %s", err)
+		return exitWithError(printer, result, err)
 	}
-	if len(unappliedMigrations) == 0 {
-		fmt.Printf("No new migrations")
-		return
+	result.Ok = true
+	if exitCode := printResult(printer, result); exitCode != ExitOK {
+		return exitCode
+	}
+	if result.Status != nil && len(result.Status.Pending) != 0 {
+		return ExitPendingMigrations
 	}
-	showUnappliedMigrations(unappliedMigrations)
+	if result.Check != nil {
+		return exitCodeOfCheck(result.Check.State)
+	}
+	return ExitOK
+}
 
-	if cfg.StringVersionDb == "" {
-		if isInitModeON {
-			fmt.Printf("WARNING. If you specify some version in -versiondb, migrations will be applied starting from %s version.",
-				ucInitMigration.GetInitMigration().VersionDb.String())
-			return
+// Returns the result of the command for -output=json
+func runCommand(ctx context.Context, runner *Runner, cfg *config.Config, printer *helper.Printer) (*document, error) {
+	result := &document{Command: cfg.Command}
+	switch cfg.Command {
+	case config.CommandPlan:
+		migrationsToMigrate, err := runner.Plan(ctx)
+		result.Planned = newMigrationGroupDocuments(migrationsToMigrate)
+		if len(migrationsToMigrate) != 0 {
+			result.RollbackStrategy = cfg.RollbackStrategy
 		}
-		if cfg.StringNameMigration == "" {
-			return
+		return result, err
+	case config.CommandUp:
+		err := runner.Up(ctx)
+		result.Migrations = newAppliedMigrationDocuments(runner.AppliedMigrations(), err)
+		return result, err
+	case config.CommandVerify:
+		mismatches, err := runner.Verify(ctx)
+		result.Mismatches = newMismatchDocuments(mismatches)
+		return result, err
+	case config.CommandRollback:
+		return result, runner.Rollback(ctx)
+	case config.CommandRestore:
+		return result, runner.Restore(ctx)
+	case config.CommandImportLegacy:
+		return result, runner.ImportLegacy(ctx)
+	case config.CommandMarkApplied:
+		changes, err := runner.MarkApplied(ctx)
+		result.Changes = newManualChangeDocuments(changes)
+		return result, err
+	case config.CommandSkip:
+		change, err := runner.Skip(ctx)
+		if change != nil {
+			result.Changes = newManualChangeDocuments([]domain.ManualChange{*change})
+		}
+		return result, err
+	case config.CommandRepair:
+		change, err := runner.Repair(ctx)
+		if change != nil {
+			result.Changes = newManualChangeDocuments([]domain.ManualChange{*change})
 		}
+		return result, err
+	case config.CommandHistory:
+		records, err := runner.History(ctx)
+		result.History = newHistoryRecordDocuments(records)
+		return result, err
+	case config.CommandBaseline:
+		baselineMigration, err := runner.Baseline(ctx)
+		if baselineMigration != nil {
+			result.Baseline = &migrationDocument{VersionDb: baselineMigration.VersionDb.String(), Name: baselineMigration.Name}
+		}
+		return result, err
 	}
 
-	lastMigrationToMigrate := determinationLastMigrationToMigrate(cfg.StringVersionDb, cfg.StringNameMigration, currentMigration, unappliedMigrations)
-	migrationsToMigrate := getMigrationGroupsAndMigrationsBeforeMigration(unappliedMigrations, lastMigrationToMigrate)
+	if cfg.IsCheck {
+		checkResult, err := runner.Check(ctx)
+		if err != nil {
+			return result, err
+		}
+		result.Check = newCheckDocument(checkResult, cfg.RequiredVersion)
+		return result, nil
+	}
 
-	sqlFromUpdateCurrentMigrationFile, err := ucFileReader.GetSqlFromUpdateCurrentMigrationFile()
+	status, err := runner.Status(ctx)
 	if err != nil {
-		log.Fatalf("Error when retrieving sql text from %s: %s", ucFileReader.ShortPathToUpdateCurrentMigrationFile, err)
+		return result, err
 	}
+	result.Status = newStatusDocument(status)
+	if status.IsInitMode && len(status.UnappliedMigrations) != 0 {
+		printer.Printf("WARNING. If you specify some version in -versiondb, migrations will be applied starting from %s version.",
+			status.CurrentMigration.VersionDb.String())
+	}
+	return result, nil
+}
 
-	infraDumpPostgres, err := dump_postgres.NewDumpPostgres(cfg.DbEntry)
-	if err != nil {
-		log.Fatalf("Error when creating infraDumpPostgres: %s", err)
+// With -output=json the result is written to stdout, otherwise nothing is written
+func printResult(printer *helper.Printer, result *document) int {
+	if err := printer.PrintDocument(result); err != nil {
+		log.Printf("error when writing the result: %s", err)
+		return ExitError
 	}
-	ucDump, err := usecase.NewDumpUseCase(infraDumpPostgres, cfg.IsVerbose)
-	if err != nil {
-		log.Fatalf("Error when creating ucDump: %s", err)
+	return ExitOK
+}
+
+// The error is written to the result and to stderr. Returns the exit code of the error.
+func exitWithError(printer *helper.Printer, result *document, err error) int {
+	result.Ok = false
+	result.Error = newErrorDocument(err)
+	printResult(printer, result)
+	log.Printf("%s", err)
+	return exitCodeOfError(err)
+}
+
+// The user confirms the action by typing the name of the database. Without a terminal the confirmation is impossible, -yes is required.
+func confirmInTerminal(printer *helper.Printer, profile string, dbName string) func(action string) error {
+	return func(action string) error {
+		info, err := os.Stdin.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return domain.WithKind(domain.ErrNotConfirmed,
+				fmt.Errorf("the %s profile is protected, specify -yes to %s without confirmation", profile, action))
+		}
+
+		printer.Printf("The %s profile is protected. To %s, type the name of the database: ", profile, action)
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error reading the confirmation: %w", err)
+		}
+		if strings.TrimSpace(answer) != dbName {
+			return domain.WithKind(domain.ErrNotConfirmed, fmt.Errorf("the action is not confirmed, the database has not been changed"))
+		}
+		return nil
 	}
+}
 
-	newDump, err := ucDump.Create(ctx)
-	if err != nil {
-		log.Fatalf("Error when creating a new dump: %s", err)
+func showSettings(printer *helper.Printer, settings []config.Setting) {
+	for _, s := range settings {
+		printer.Printf("%-14s %-30s (%s)\n", s.Name, s.Value, s.Source)
 	}
+}
 
-	setupCloseHandler(ucDump, newDump, conn, ctx)
+func showCurrentMigration(printer *helper.Printer, currentMigration *domain.Migration) {
+	printer.Printf("Current database version: %s\n"+
+		"Last migration applied: %s\n", currentMigration.VersionDb.String(), currentMigration.Name)
+}
 
-	ucMigrate := usecase.NewMigrateUseCase(repoMigrationDisk, repoMigrationPostgres, cfg.IsVerbose)
-	if err := ucMigrate.Migrate(ctx, cfg.PathToMigrations, migrationsToMigrate); err != nil {
-		fmt.Printf("Error when applying migrations: %v\n", err)
-		conn.Close(ctx)
-		if errFromRestore := ucDump.RestoreDatabaseFromDumpAndDeleteDump(ctx, newDump); errFromRestore != nil {
-			err := ucDump.GetErrorForBadRestore(newDump)
-			log.Fatalf("Error when restoring database from dump: %s: %s", errFromRestore, err)
+func showUnappliedMigrations(printer *helper.Printer, migrationGroups []domain.MigrationGroup) {
+	printer.Println("Updates available:")
+
+	colorGreen := "\033[32m"
+	colorReset := "\033[0m"
+
+	printer.Printf("%s", string(colorGreen))
+	for _, mg := range migrationGroups {
+		printer.Printf("\n%s\n", mg.VersionDb.String())
+		for i := 0; i < len(mg.Migrations); i++ {
+			if mg.Migrations[i].IsNoTransaction {
+				printer.Printf("    %s (no transaction)\n", mg.Migrations[i].Name)
+				continue
+			}
+			printer.Printf("    %s\n", mg.Migrations[i].Name)
 		}
-		return
 	}
+	printer.Println(string(colorReset))
+}
 
-	lastMigrationGroup := migrationsToMigrate[len(migrationsToMigrate)-1]
-	lastAppliedMigration := lastMigrationGroup.Migrations[len(lastMigrationGroup.Migrations)-1]
-	if err := ucMigrationCurrent.UpdateCurrentMigration(ctx, sqlFromUpdateCurrentMigrationFile, &lastAppliedMigration); err != nil {
-		fmt.Printf("Error when executing a query from %s: %v\n", ucFileReader.ShortPathToUpdateCurrentMigrationFile, err)
-		conn.Close(ctx)
-		if errFromRestore := ucDump.RestoreDatabaseFromDumpAndDeleteDump(ctx, newDump); errFromRestore != nil {
-			err := ucDump.GetErrorForBadRestore(newDump)
-			log.Fatalf("Error when restoring database from dump: %s: %s", errFromRestore, err)
+func showPlan(printer *helper.Printer, migrationsToMigrate []domain.MigrationGroup, rollbackStrategy string) {
+	printer.Println("Migrations to apply:")
+
+	colorGreen := "\033[32m"
+	colorReset := "\033[0m"
+
+	printer.Printf("%s", string(colorGreen))
+	for _, mg := range migrationsToMigrate {
+		printer.Printf("\n%s\n", mg.VersionDb.String())
+		for i := 0; i < len(mg.Migrations); i++ {
+			if mg.Migrations[i].IsNoTransaction {
+				printer.Printf("    %s (without a transaction)\n", mg.Migrations[i].Name)
+				continue
+			}
+			printer.Printf("    %s\n", mg.Migrations[i].Name)
 		}
-		return
 	}
+	printer.Println(string(colorReset))
+	printer.Printf("Rollback strategy: %s\n", rollbackStrategy)
+}
 
-	if err := os.Remove(newDump.Path()); err != nil {
-		fmt.Printf("Error when deleting dump file: %s\n", err)
+func showCheckResult(printer *helper.Printer, result *domain.CheckResult) {
+	colorGreen := "\033[32m"
+	colorRed := "\033[31m"
+	colorReset := "\033[0m"
+
+	if result.State == domain.CheckStateMatch {
+		printer.Printf("Check: %s%s%s\n", colorGreen, result.State, colorReset)
 		return
 	}
-	helper.ShowIfVerbose(cfg.IsVerbose, "Dump deleted.")
+	printer.Printf("Check: %s%s%s, %s\n", colorRed, result.State, colorReset, result.Reason)
+}
 
-	conn.Close(ctx)
-	return
+func showSqlOfMigration(printer *helper.Printer, migration *domain.Migration, sql string) {
+	printer.Printf("\n-- %s %s\n%s\n", migration.VersionDb.String(), migration.Name, strings.TrimRight(sql, "\n"))
 }
 
-func showCurrentMigration(currentMigration *domain.Migration) {
-	fmt.Printf("Current database version: %s\n"+
-		"Last migration applied: %s\n", currentMigration.VersionDb.String(), currentMigration.Name)
+func showDumpInPlan(printer *helper.Printer, rollbackStrategy string, descriptionOfUtilities string) {
+	if rollbackStrategy == config.RollbackDump {
+		printer.Println("A dump is created before applying, if an error occurs the database is restored from it.")
+	} else {
+		printer.Println("A dump is created before each migration without a transaction.")
+	}
+	printer.Println(descriptionOfUtilities)
+}
+
+func showCurrentMigrationAfterPlan(printer *helper.Printer, lastMigration *domain.Migration, trackingTable string) {
+	printer.Printf("After applying the current migration in %s will be %s %s\n",
+		trackingTable, lastMigration.VersionDb.String(), lastMigration.Name)
 }
 
-func showUnappliedMigrations(migrationGroups []domain.MigrationGroup) {
-	fmt.Println("Updates available:")
+func showMigrationsToRollback(printer *helper.Printer, migrationGroups []domain.MigrationGroup, targetMigration *domain.Migration) {
+	printer.Println("Migrations to roll back:")
 
-	colorGreen := "\033[32m"
+	colorYellow := "\033[33m"
 	colorReset := "\033[0m"
 
-	fmt.Print(string(colorGreen))
+	printer.Printf("%s", string(colorYellow))
 	for _, mg := range migrationGroups {
-		fmt.Printf("\n%s\n", mg.VersionDb.String())
+		printer.Printf("\n%s\n", mg.VersionDb.String())
 		for i := 0; i < len(mg.Migrations); i++ {
-			fmt.Printf("    %s\n", mg.Migrations[i].Name)
+			if mg.Migrations[i].IsSkipped {
+				printer.Printf("    %s (skipped, the down script is not executed)\n", mg.Migrations[i].Name)
+				continue
+			}
+			if mg.Migrations[i].IsDownNoTransaction {
+				printer.Printf("    %s (without a transaction)\n", mg.Migrations[i].Name)
+				continue
+			}
+			printer.Printf("    %s\n", mg.Migrations[i].Name)
 		}
 	}
-	fmt.Println(string(colorReset))
+	printer.Println(string(colorReset))
+	printer.Printf("After the rollback the current migration will be %s %s\n", targetMigration.VersionDb.String(), targetMigration.Name)
+}
+
+func showBaseline(printer *helper.Printer, baselineMigration *domain.Migration) {
+	printer.Printf("The %s %s migration will be recorded as the current one, nothing will be executed\n",
+		baselineMigration.VersionDb.String(), baselineMigration.Name)
 }
 
-func checkRequiredParameters(cfg *config.Config) {
-	if cfg.DbEntry.Host == "" || cfg.DbEntry.Port == "" || cfg.DbEntry.DbName == "" ||
-		cfg.DbEntry.User == "" || cfg.DbEntry.Password == "" {
-		log.Fatalf("Not all parameters for connection are specified. Familiarize yourself with them using -help.")
+func showManualChanges(printer *helper.Printer, changes []domain.ManualChange) {
+	printer.Println("The current migration is changed without executing migrations:")
+
+	colorYellow := "\033[33m"
+	colorReset := "\033[0m"
+
+	printer.Printf("%s", string(colorYellow))
+	for _, change := range changes {
+		switch change.Kind {
+		case domain.ManualChangeMarkApplied:
+			printer.Printf("    %s %s - applied by hand\n", change.Migration.VersionDb.String(), change.Migration.Name)
+		case domain.ManualChangeSkip:
+			printer.Printf("    %s %s - skipped\n", change.Migration.VersionDb.String(), change.Migration.Name)
+		case domain.ManualChangeRepair:
+			printer.Printf("    %s %s - set as the current migration\n", change.Migration.VersionDb.String(), change.Migration.Name)
+		}
 	}
-	if cfg.PathToMigrations == "" {
-		log.Fatalf("To view the current version of the database, the last applied migration, " +
-			"apply new migrations, specify the path to the directory with migration scripts in the -migrations parameter")
+	printer.Println(string(colorReset))
+	printer.Printf("Reason: %s\n", changes[0].Reason)
+}
+
+func showHistory(printer *helper.Printer, records []domain.HistoryRecord) {
+	if len(records) == 0 {
+		printer.Println("No records in the history")
+		return
 	}
+
+	var table strings.Builder
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Time\tVersion\tMigration\tOutcome\tDuration\tUser\tClient host\tTool version\tReason")
+	for _, record := range records {
+		clientHost := record.ClientHost
+		if clientHost == "" {
+			clientHost = "-"
+		}
+		reason := record.Reason
+		if record.ChangedBy != "" {
+			reason = fmt.Sprintf("%s (%s)", reason, record.ChangedBy)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", record.At.Local().Format("2006-01-02 15:04:05"),
+			record.Migration.VersionDb.String(), record.Migration.Name, strings.ReplaceAll(record.Outcome, "_", " "),
+			record.Duration, record.DbUser, clientHost, record.ToolVersion, reason)
+	}
+	w.Flush()
+	printer.Printf("%s", table.String())
 }
 
-// Restore database from dump at Ctrl+C
-func setupCloseHandler(ucDump *usecase.DumpUseCase, dump *domain.Dump, conn *pgx.Conn, ctx context.Context) {
+func showChecksumMismatches(printer *helper.Printer, mismatches []domain.ChecksumMismatch) {
+	printer.Println("The files of applied migrations have been changed:")
+
+	colorRed := "\033[31m"
+	colorReset := "\033[0m"
+
+	printer.Printf("%s", string(colorRed))
+	for _, mismatch := range mismatches {
+		if mismatch.IsFileMissing() {
+			printer.Printf("    %s %s - the file has been deleted\n", mismatch.Migration.VersionDb.String(), mismatch.Migration.Name)
+			continue
+		}
+		printer.Printf("    %s %s - recorded checksum %s, actual %s\n", mismatch.Migration.VersionDb.String(), mismatch.Migration.Name,
+			mismatch.RecordedChecksum, mismatch.ActualChecksum)
+	}
+	printer.Printf("%s", string(colorReset))
+}
+
+// At Ctrl+C the connection is closed, the server rolls back the open transaction.
+// Returns the function that removes the handler.
+func setupCloseHandlerForTransaction(conn *pgx.Conn, ctx context.Context, exitCode int) func() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	go func() {
-		<-c
+		if _, ok := <-c; !ok {
+			return
+		}
+		fmt.Println("\r- Ctrl+C pressed in Terminal")
+
+		conn.Close(ctx)
+
+		log.Printf("The connection is closed, the open transaction is rolled back by the server.")
+		os.Exit(exitCode)
+	}()
+	return func() {
+		signal.Stop(c)
+		close(c)
+	}
+}
+
+// Restore database from dump at Ctrl+C. Returns the function that removes the handler.
+func setupCloseHandler(ucDump *usecase.DumpUseCase, dump *domain.Dump, conn *pgx.Conn, ctx context.Context) func() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		if _, ok := <-c; !ok {
+			return
+		}
 		fmt.Println("\r- Ctrl+C pressed in Terminal")
 
 		conn.Close(ctx)
 
 		err := ucDump.GetErrorForBadRestore(dump)
-		log.Fatalf("An error may have occurred when applying migrations: %s", err)
+		log.Printf("An error may have occurred when applying migrations: %s", err)
+		os.Exit(ExitRestoreFailed)
 	}()
+	return func() {
+		signal.Stop(c)
+		close(c)
+	}
 }
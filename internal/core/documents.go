@@ -0,0 +1,395 @@
+package core
+
+// The file is used to describe the documents written with -output=json.
+// One document is written to stdout when the command finishes, the messages about the progress are written to stderr.
+
+import (
+	"errors"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	migrationStatusApplied = "applied"
+	migrationStatusFailed  = "failed"
+)
+
+// The kinds of errors in the order of checking, the first one found is written
+var errorKinds = []struct {
+	kind error
+	name string
+}{
+	{kind: domain.ErrRestoreFailed, name: "restore_failed"},
+	{kind: domain.ErrPartiallyApplied, name: "partially_applied"},
+	{kind: domain.ErrApplyFailed, name: "apply_failed"},
+	{kind: domain.ErrPlanMismatch, name: "plan_mismatch"},
+	{kind: domain.ErrChecksumMismatch, name: "checksum_mismatch"},
+	{kind: domain.ErrLocked, name: "locked"},
+	{kind: domain.ErrNotConfirmed, name: "not_confirmed"},
+	{kind: domain.ErrInvalidTarget, name: "invalid_target"},
+	{kind: domain.ErrNotFound, name: "not_found"},
+	{kind: domain.ErrConnectionFailed, name: "connection_failed"},
+	{kind: domain.ErrInvalidParameters, name: "invalid_parameters"},
+}
+
+// document is the result of any command, only the fields of the executed command are filled
+type document struct {
+	Command string         `json:"command"`
+	Ok      bool           `json:"ok"`
+	Error   *errorDocument `json:"error,omitempty"`
+
+	// status
+	Status *statusDocument `json:"status,omitempty"`
+
+	// status -check
+	Check *checkDocument `json:"check,omitempty"`
+
+	// plan
+	Planned          []migrationGroupDocument `json:"planned,omitempty"`
+	RollbackStrategy string                   `json:"rollback_strategy,omitempty"`
+
+	// up
+	Migrations []appliedMigrationDocument `json:"migrations,omitempty"`
+
+	// verify
+	Mismatches []mismatchDocument `json:"mismatches,omitempty"`
+
+	// mark-applied, skip, repair
+	Changes []manualChangeDocument `json:"changes,omitempty"`
+
+	// history
+	History []historyRecordDocument `json:"history,omitempty"`
+
+	// baseline
+	Baseline *migrationDocument `json:"baseline,omitempty"`
+
+	// new, new-version
+	Created []string `json:"created,omitempty"`
+
+	// config show
+	Settings []settingDocument `json:"settings,omitempty"`
+
+	// version
+	Version string `json:"version,omitempty"`
+}
+
+type statusDocument struct {
+	IsInitMode       bool                     `json:"is_init_mode"`
+	CurrentVersionDb string                   `json:"current_version_db"`
+	CurrentMigration string                   `json:"current_migration"`
+	Pending          []migrationGroupDocument `json:"pending"`
+}
+
+type checkDocument struct {
+	// One of match, behind, ahead_or_unknown
+	State           string `json:"state"`
+	Reason          string `json:"reason,omitempty"`
+	RequiredVersion string `json:"required_version,omitempty"`
+
+	// Empty in initialization mode
+	CurrentVersionDb string `json:"current_version_db"`
+	CurrentMigration string `json:"current_migration"`
+
+	// Empty if there are no migrations in the directory
+	LatestVersionDb string `json:"latest_version_db"`
+	LatestMigration string `json:"latest_migration"`
+}
+
+type migrationDocument struct {
+	VersionDb string `json:"version_db"`
+	Name      string `json:"name"`
+}
+
+type migrationGroupDocument struct {
+	VersionDb  string   `json:"version_db"`
+	Migrations []string `json:"migrations"`
+}
+
+type appliedMigrationDocument struct {
+	VersionDb  string         `json:"version_db"`
+	Name       string         `json:"name"`
+	Status     string         `json:"status"`
+	DurationMs int64          `json:"duration_ms"`
+	Checksum   string         `json:"checksum,omitempty"`
+	Error      *errorDocument `json:"error,omitempty"`
+}
+
+type mismatchDocument struct {
+	VersionDb        string `json:"version_db"`
+	Name             string `json:"name"`
+	RecordedChecksum string `json:"recorded_checksum"`
+	ActualChecksum   string `json:"actual_checksum"`
+	IsFileMissing    bool   `json:"is_file_missing"`
+}
+
+type manualChangeDocument struct {
+	VersionDb string `json:"version_db"`
+	Name      string `json:"name"`
+
+	// One of mark_applied, skip, repair
+	Kind      string `json:"kind"`
+	Checksum  string `json:"checksum,omitempty"`
+	Reason    string `json:"reason"`
+	ChangedBy string `json:"changed_by"`
+}
+
+type historyRecordDocument struct {
+	At        time.Time `json:"at"`
+	VersionDb string    `json:"version_db"`
+	Name      string    `json:"name"`
+
+	// One of applied, rolled_back, imported, baseline, mark_applied, skip, repair
+	Outcome string `json:"outcome"`
+
+	// The migration that became current, only for rolled_back
+	CurrentVersionDb string `json:"current_version_db,omitempty"`
+	CurrentMigration string `json:"current_migration,omitempty"`
+
+	DurationMs  int64  `json:"duration_ms"`
+	DbUser      string `json:"db_user"`
+	ClientHost  string `json:"client_host"`
+	ToolVersion string `json:"tool_version"`
+	Checksum    string `json:"checksum,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	ChangedBy   string `json:"changed_by,omitempty"`
+}
+
+type settingDocument struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+type errorDocument struct {
+	Message string `json:"message"`
+
+	// One of the names in errorKinds, empty if the kind is not known
+	Kind string `json:"kind,omitempty"`
+
+	// The fields of the error reported by PostgreSQL
+	SqlState   string `json:"sqlstate,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+	Hint       string `json:"hint,omitempty"`
+	Schema     string `json:"schema,omitempty"`
+	Table      string `json:"table,omitempty"`
+	Column     string `json:"column,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+
+	// The place of the error in the migration file
+	Position *positionDocument `json:"position,omitempty"`
+
+	// The failed statement if the statements are executed one by one with -statements
+	Statement *statementDocument `json:"statement,omitempty"`
+}
+
+type statementDocument struct {
+	// From 1
+	Number int `json:"number"`
+	Count  int `json:"count"`
+
+	// The line of the beginning of the statement in the migration file
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+type positionDocument struct {
+	// From 1
+	Line   int `json:"line"`
+	Column int `json:"column"`
+
+	// The line of the error with the lines around it
+	Lines []sqlLineDocument `json:"lines"`
+}
+
+type sqlLineDocument struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+}
+
+func newErrorDocument(err error) *errorDocument {
+	errDocument := &errorDocument{
+		Message: err.Error(),
+	}
+	for _, errorKind := range errorKinds {
+		if errors.Is(err, errorKind.kind) {
+			errDocument.Kind = errorKind.name
+			break
+		}
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		errDocument.SqlState = pgErr.Code
+		errDocument.Detail = pgErr.Detail
+		errDocument.Hint = pgErr.Hint
+		errDocument.Schema = pgErr.SchemaName
+		errDocument.Table = pgErr.TableName
+		errDocument.Column = pgErr.ColumnName
+		errDocument.Constraint = pgErr.ConstraintName
+	}
+	var migrationErr *domain.MigrationError
+	if errors.As(err, &migrationErr) {
+		if migrationErr.Position != nil {
+			errDocument.Position = newPositionDocument(migrationErr.Position)
+		}
+		if migrationErr.Statement != nil {
+			errDocument.Statement = &statementDocument{
+				Number: migrationErr.Statement.Number,
+				Count:  migrationErr.Statement.Count,
+				Line:   migrationErr.Statement.Statement.Line,
+				Text:   migrationErr.Statement.Statement.Text,
+			}
+		}
+	}
+	return errDocument
+}
+
+func newPositionDocument(position *domain.SqlPosition) *positionDocument {
+	lines := make([]sqlLineDocument, 0, len(position.Lines))
+	for _, line := range position.Lines {
+		lines = append(lines, sqlLineDocument{
+			Number: line.Number,
+			Text:   line.Text,
+		})
+	}
+	return &positionDocument{
+		Line:   position.Line,
+		Column: position.Column,
+		Lines:  lines,
+	}
+}
+
+func newStatusDocument(status *Status) *statusDocument {
+	return &statusDocument{
+		IsInitMode:       status.IsInitMode,
+		CurrentVersionDb: status.CurrentMigration.VersionDb.String(),
+		CurrentMigration: status.CurrentMigration.Name,
+		Pending:          newMigrationGroupDocuments(status.UnappliedMigrations),
+	}
+}
+
+func newCheckDocument(result *domain.CheckResult, requiredVersion string) *checkDocument {
+	checkDoc := &checkDocument{
+		State:           result.State,
+		Reason:          result.Reason,
+		RequiredVersion: requiredVersion,
+	}
+	if result.CurrentMigration != nil {
+		checkDoc.CurrentVersionDb = result.CurrentMigration.VersionDb.String()
+		checkDoc.CurrentMigration = result.CurrentMigration.Name
+	}
+	if result.LatestMigration != nil {
+		checkDoc.LatestVersionDb = result.LatestMigration.VersionDb.String()
+		checkDoc.LatestMigration = result.LatestMigration.Name
+	}
+	return checkDoc
+}
+
+func newMigrationGroupDocuments(migrationGroups []domain.MigrationGroup) []migrationGroupDocument {
+	documents := make([]migrationGroupDocument, 0, len(migrationGroups))
+	for _, mg := range migrationGroups {
+		names := make([]string, 0, len(mg.Migrations))
+		for _, migration := range mg.Migrations {
+			names = append(names, migration.Name)
+		}
+		documents = append(documents, migrationGroupDocument{
+			VersionDb:  mg.VersionDb.String(),
+			Migrations: names,
+		})
+	}
+	return documents
+}
+
+// The applied migrations are followed by the migration that has failed, if the error is about a migration
+func newAppliedMigrationDocuments(appliedMigrations []domain.AppliedMigration, err error) []appliedMigrationDocument {
+	documents := make([]appliedMigrationDocument, 0, len(appliedMigrations)+1)
+	for _, appliedMigration := range appliedMigrations {
+		documents = append(documents, appliedMigrationDocument{
+			VersionDb:  appliedMigration.Migration.VersionDb.String(),
+			Name:       appliedMigration.Migration.Name,
+			Status:     migrationStatusApplied,
+			DurationMs: appliedMigration.Duration.Milliseconds(),
+			Checksum:   appliedMigration.Checksum,
+		})
+	}
+
+	var migrationErr *domain.MigrationError
+	if errors.As(err, &migrationErr) {
+		documents = append(documents, appliedMigrationDocument{
+			VersionDb: migrationErr.Migration.VersionDb.String(),
+			Name:      migrationErr.Migration.Name,
+			Status:    migrationStatusFailed,
+			Error:     newErrorDocument(migrationErr),
+		})
+	}
+	return documents
+}
+
+func newMismatchDocuments(mismatches []domain.ChecksumMismatch) []mismatchDocument {
+	documents := make([]mismatchDocument, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		documents = append(documents, mismatchDocument{
+			VersionDb:        mismatch.Migration.VersionDb.String(),
+			Name:             mismatch.Migration.Name,
+			RecordedChecksum: mismatch.RecordedChecksum,
+			ActualChecksum:   mismatch.ActualChecksum,
+			IsFileMissing:    mismatch.IsFileMissing(),
+		})
+	}
+	return documents
+}
+
+func newManualChangeDocuments(changes []domain.ManualChange) []manualChangeDocument {
+	documents := make([]manualChangeDocument, 0, len(changes))
+	for _, change := range changes {
+		documents = append(documents, manualChangeDocument{
+			VersionDb: change.Migration.VersionDb.String(),
+			Name:      change.Migration.Name,
+			Kind:      change.Kind,
+			Checksum:  change.Checksum,
+			Reason:    change.Reason,
+			ChangedBy: change.ChangedBy,
+		})
+	}
+	return documents
+}
+
+func newHistoryRecordDocuments(records []domain.HistoryRecord) []historyRecordDocument {
+	documents := make([]historyRecordDocument, 0, len(records))
+	for _, record := range records {
+		recordDoc := historyRecordDocument{
+			At:          record.At,
+			VersionDb:   record.Migration.VersionDb.String(),
+			Name:        record.Migration.Name,
+			Outcome:     record.Outcome,
+			DurationMs:  record.Duration.Milliseconds(),
+			DbUser:      record.DbUser,
+			ClientHost:  record.ClientHost,
+			ToolVersion: record.ToolVersion,
+			Checksum:    record.Checksum,
+			Reason:      record.Reason,
+			ChangedBy:   record.ChangedBy,
+		}
+		if record.Outcome == domain.HistoryOutcomeRolledBack {
+			recordDoc.CurrentVersionDb = record.CurrentMigration.VersionDb.String()
+			recordDoc.CurrentMigration = record.CurrentMigration.Name
+		}
+		documents = append(documents, recordDoc)
+	}
+	return documents
+}
+
+func newSettingDocuments(settings []config.Setting) []settingDocument {
+	documents := make([]settingDocument, 0, len(settings))
+	for _, s := range settings {
+		documents = append(documents, settingDocument{
+			Name:   s.Name,
+			Value:  s.Value,
+			Source: s.Source,
+		})
+	}
+	return documents
+}
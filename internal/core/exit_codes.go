@@ -0,0 +1,68 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+// The exit codes of dbupdater. Scripts and CI rely on them, the values are not changed.
+const (
+	// The command has been executed. status: there are no new migrations.
+	ExitOK = 0
+
+	// An error that has no code of its own
+	ExitError = 1
+
+	// Wrong flags, config file or environment variables
+	ExitConfigError = 2
+
+	// The connection to the database has not been established
+	ExitConnectionError = 3
+
+	// status: there are new migrations. status -check: the database is behind.
+	ExitPendingMigrations = 4
+
+	// The migrations have not been applied, the database has been returned to its previous state
+	ExitApplyFailed = 5
+
+	// The database has not been returned to its previous state, it must be restored manually
+	ExitRestoreFailed = 6
+
+	// status -check: the current migration is newer than expected or is not in the directory with migrations
+	ExitAheadOrUnknown = 7
+
+	// The failed part of the migrations has not been applied, the parts committed before it remain applied
+	ExitPartiallyApplied = 8
+)
+
+// The kinds of errors in the order of checking. The errors of other kinds exit with ExitError.
+var exitCodesOfErrors = []struct {
+	kind     error
+	exitCode int
+}{
+	{kind: domain.ErrRestoreFailed, exitCode: ExitRestoreFailed},
+	{kind: domain.ErrPartiallyApplied, exitCode: ExitPartiallyApplied},
+	{kind: domain.ErrApplyFailed, exitCode: ExitApplyFailed},
+	{kind: domain.ErrConnectionFailed, exitCode: ExitConnectionError},
+	{kind: domain.ErrInvalidParameters, exitCode: ExitConfigError},
+}
+
+func exitCodeOfCheck(state string) int {
+	switch state {
+	case domain.CheckStateMatch:
+		return ExitOK
+	case domain.CheckStateBehind:
+		return ExitPendingMigrations
+	}
+	return ExitAheadOrUnknown
+}
+
+func exitCodeOfError(err error) int {
+	for _, e := range exitCodesOfErrors {
+		if errors.Is(err, e.kind) {
+			return e.exitCode
+		}
+	}
+	return ExitError
+}
@@ -0,0 +1,1124 @@
+package core
+
+// The file is used to describe the algorithms of the commands. The methods return errors instead of exiting,
+// so the commands can be executed by applications, for example to apply migrations at startup.
+// It is forbidden to call infrastructure methods.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/internal/usecase"
+
+	"github.com/awsoremod/DBUpdater/internal/infrastructure/dump_postgres"
+	"github.com/awsoremod/DBUpdater/internal/infrastructure/repo/migration_disk"
+	"github.com/awsoremod/DBUpdater/internal/infrastructure/repo/migration_postgres"
+	"github.com/awsoremod/DBUpdater/internal/infrastructure/repo/plan_file"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Runner executes the commands on one connection to the database.
+// The kind of the returned errors can be checked with errors.Is: domain.ErrNotFound, domain.ErrInvalidTarget,
+// domain.ErrApplyFailed, domain.ErrPartiallyApplied, domain.ErrRestoreFailed, domain.ErrChecksumMismatch, domain.ErrLocked,
+// domain.ErrNotConfirmed, domain.ErrPlanMismatch, domain.ErrConnectionFailed, domain.ErrInvalidParameters.
+type Runner struct {
+	cfg     *config.Config
+	conn    *pgx.Conn
+	printer *helper.Printer
+
+	// Restore the database or close the transaction at Ctrl+C
+	isHandleInterrupt bool
+
+	// If it is set, it is called before the database is changed. The changes are not made if it returns an error.
+	confirm func(action string) error
+
+	isLegacyTrackingON bool
+
+	// The migrations applied by Up that remain applied, the reverted changes are removed
+	appliedMigrations []domain.AppliedMigration
+
+	// A part of the changes of the command has been committed, a failure no longer returns the database to the state
+	// before the command
+	isPartlyChanged bool
+
+	// Releases the archive with migrations
+	closeMigrationsFS func() error
+
+	repoMigrationPostgres *migration_postgres.MigrationPostgresRepo
+	repoMigrationDisk     *migration_disk.MigrationDiskRepo
+	repoLock              *migration_postgres.LockPostgresRepo
+
+	ucFileReader       *usecase.FileReaderUseCase
+	ucMigrationCurrent *usecase.MigrationCurrentUseCase
+	ucInitMigration    *usecase.InitMigrationUseCase
+}
+
+// Status is the state of the database relative to the directory with migrations
+type Status struct {
+	IsInitMode       bool
+	CurrentMigration *domain.Migration
+
+	// Migrations after the current one in ascending order
+	UnappliedMigrations []domain.MigrationGroup
+}
+
+// Connects to the database. The connection must be closed with Close. The messages are shown with the printer.
+func NewRunner(ctx context.Context, cfg *config.Config, printer *helper.Printer) (*Runner, error) {
+	if err := checkRequiredParameters(cfg); err != nil {
+		return nil, err
+	}
+
+	printer.ShowIfVerbose("Connecting to the database...")
+	conn, err := helper.OpenConnect(ctx, &cfg.DbEntry, false)
+	if err != nil {
+		return nil, domain.WithKind(domain.ErrConnectionFailed, fmt.Errorf("error when connecting to the database: %w", err))
+	}
+	printer.ShowIfVerbose("Connection established.")
+	// The name of the database could be taken from the connection string, the service file or PGDATABASE,
+	// it is needed for the lock, the confirmation and the names of the dumps
+	resolvedCfg := *cfg
+	resolvedCfg.DbEntry.DbName = conn.Config().Database
+	cfg = &resolvedCfg
+
+	ucInitMigration, err := usecase.NewInitMigrationUseCase()
+	if err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("error when creating ucInitMigration: %w", err)
+	}
+
+	migrationsFS, closeMigrationsFS, err := openMigrationsFS(cfg)
+	if err != nil {
+		conn.Close(ctx)
+		return nil, domain.WithKind(domain.ErrNotFound, fmt.Errorf("error when opening the migrations: %w", err))
+	}
+
+	ucFileReader := usecase.NewFileReaderUseCase(migrationsFS, printer)
+	isLegacyTrackingON := isLegacyTracking(cfg.TrackingMode, ucFileReader)
+	repoMigrationCurrent := newRepoMigrationCurrent(cfg, conn, ucFileReader, isLegacyTrackingON)
+
+	return &Runner{
+		cfg:                   cfg,
+		conn:                  conn,
+		printer:               printer,
+		isLegacyTrackingON:    isLegacyTrackingON,
+		closeMigrationsFS:     closeMigrationsFS,
+		repoMigrationPostgres: migration_postgres.NewMigrationPostgresRepo(conn),
+		repoMigrationDisk:     migration_disk.NewMigrationDiskRepoo(migrationsFS, printer),
+		repoLock:              migration_postgres.NewLockPostgresRepo(conn),
+		ucFileReader:          ucFileReader,
+		ucMigrationCurrent:    usecase.NewMigrationCurrentUseCase(repoMigrationCurrent, printer),
+		ucInitMigration:       ucInitMigration,
+	}, nil
+}
+
+// At Ctrl+C the database is restored from the dump or the open transaction is rolled back, then the process exits.
+// Applications that handle signals themselves should not call it.
+func (r *Runner) HandleInterrupt() {
+	r.isHandleInterrupt = true
+}
+
+// The confirm function is called with the description of the action after the changes are shown and before they are made.
+// If it returns an error, the database is not changed.
+func (r *Runner) SetConfirmation(confirm func(action string) error) {
+	r.confirm = confirm
+}
+
+// Closes the connection to the database and releases the archive with migrations
+func (r *Runner) Close(ctx context.Context) error {
+	errFromClose := r.closeMigrationsFS()
+	if err := r.conn.Close(ctx); err != nil {
+		return err
+	}
+	return errFromClose
+}
+
+// Returns the current migration and the migrations available for updating
+func (r *Runner) Status(ctx context.Context) (*Status, error) {
+	isInitModeON, currentMigration, err := r.currentMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ucMigrations := usecase.NewMigrationsUseCase(r.repoMigrationDisk, r.printer)
+	unappliedMigrations, err := ucMigrations.GetUnappliedSortedMigrations(ctx, isInitModeON, currentMigration)
+	if err != nil {
+		return nil, fmt.Errorf("error when receiving unapplied migrations: %w", err)
+	}
+	if len(unappliedMigrations) == 0 {
+		r.printer.Printf("No new migrations")
+	} else {
+		showUnappliedMigrations(r.printer, unappliedMigrations)
+	}
+
+	return &Status{
+		IsInitMode:          isInitModeON,
+		CurrentMigration:    currentMigration,
+		UnappliedMigrations: unappliedMigrations,
+	}, nil
+}
+
+// Compares the current migration with the last migration in the directory or, with -require, the current version
+// with the required one. The database is not changed.
+func (r *Runner) Check(ctx context.Context) (*domain.CheckResult, error) {
+	var requiredVersion *domain.VersionConstraint
+	if r.cfg.RequiredVersion != "" {
+		var err error
+		requiredVersion, err = domain.NewVersionConstraint(r.cfg.RequiredVersion)
+		if err != nil {
+			return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("wrong version in -require: %w", err))
+		}
+	}
+
+	isInitModeON, currentMigration, err := r.currentMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ucCheck := usecase.NewCheckUseCase(r.repoMigrationDisk, r.printer)
+	result, err := ucCheck.Check(ctx, isInitModeON, currentMigration, requiredVersion)
+	if err != nil {
+		return nil, fmt.Errorf("error when checking the current migration: %w", err)
+	}
+	showCheckResult(r.printer, result)
+	return result, nil
+}
+
+// Returns the migrations that Up would apply. The database is not changed and no dump is created.
+// Shows the migrations in the order of applying, with -sql together with their sql text, the rollback strategy,
+// the dumps that would be created and the current migration after applying.
+// Returns an error if Up would refuse to apply the migrations: the target is wrong, the files of applied migrations
+// have been changed, a migration file cannot be read, pg_dump or pg_restore are not found.
+// With -out the plan is saved to the file, Up with -plan applies exactly it.
+func (r *Runner) Plan(ctx context.Context) ([]domain.MigrationGroup, error) {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(status.UnappliedMigrations) == 0 {
+		return nil, r.savePlan(ctx, status, nil)
+	}
+
+	migrationsToMigrate, err := r.getMigrationsToMigrate(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	showPlan(r.printer, migrationsToMigrate, r.cfg.RollbackStrategy)
+
+	ucMigrate := usecase.NewMigrateUseCase(r.repoMigrationDisk, r.repoMigrationPostgres, r.printer)
+	for _, mg := range migrationsToMigrate {
+		for i := range mg.Migrations {
+			sql, err := ucMigrate.GetSql(ctx, &mg.Migrations[i])
+			if err != nil {
+				return nil, err
+			}
+			if r.cfg.IsShowSql {
+				showSqlOfMigration(r.printer, &mg.Migrations[i], sql)
+			}
+		}
+	}
+
+	if isDumpNeeded(r.cfg.RollbackStrategy, migrationsToMigrate) {
+		ucDump, err := r.newDumpUseCase()
+		if err != nil {
+			return nil, err
+		}
+		showDumpInPlan(r.printer, r.cfg.RollbackStrategy, ucDump.GetDescriptionOfUtilities())
+	}
+
+	lastGroup := migrationsToMigrate[len(migrationsToMigrate)-1]
+	showCurrentMigrationAfterPlan(r.printer, &lastGroup.Migrations[len(lastGroup.Migrations)-1], r.trackingTable())
+	if err := r.savePlan(ctx, status, migrationsToMigrate); err != nil {
+		return nil, err
+	}
+	return migrationsToMigrate, nil
+}
+
+// Applies the migrations up to the one specified in -versiondb and -migration, without them all new migrations.
+// If the migrations have not been applied and the database has been returned to its previous state, domain.ErrApplyFailed is returned.
+// If the parts committed before the failed one remain applied, domain.ErrPartiallyApplied is returned.
+// If another dbupdater is changing the database, domain.ErrLocked is returned.
+// With -plan the migrations of the saved plan are applied, if the current migration or the migration files
+// differ from the plan, domain.ErrPlanMismatch is returned.
+func (r *Runner) Up(ctx context.Context) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	status, err := r.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var migrationsToMigrate []domain.MigrationGroup
+	// With -plan the sql text checked against the plan is executed, not the files read again
+	var getSqlRepo usecase.GetSqlFromRepo = r.repoMigrationDisk
+	if r.cfg.PathToPlan != "" {
+		var verifiedSql *usecase.VerifiedSql
+		migrationsToMigrate, verifiedSql, err = r.getMigrationsFromSavedPlan(ctx, status)
+		if verifiedSql != nil {
+			getSqlRepo = verifiedSql
+		}
+	} else if len(status.UnappliedMigrations) != 0 {
+		migrationsToMigrate, err = r.getMigrationsToMigrate(ctx, status)
+	}
+	if err != nil || len(migrationsToMigrate) == 0 {
+		return err
+	}
+	if r.confirm != nil {
+		showPlan(r.printer, migrationsToMigrate, r.cfg.RollbackStrategy)
+		if err := r.confirm(fmt.Sprintf("apply the migrations to the %s database", r.cfg.DbName)); err != nil {
+			return err
+		}
+	}
+
+	ucMigrate := usecase.NewMigrateUseCase(getSqlRepo, r.repoMigrationPostgres, r.printer)
+	switch r.cfg.RollbackStrategy {
+	case config.RollbackTransaction:
+		return r.migrateInTransactions(ctx, ucMigrate, [][]domain.MigrationGroup{migrationsToMigrate})
+	case config.RollbackTransactionPerVersion:
+		batches := make([][]domain.MigrationGroup, 0, len(migrationsToMigrate))
+		for _, mg := range migrationsToMigrate {
+			batches = append(batches, []domain.MigrationGroup{mg})
+		}
+		return r.migrateInTransactions(ctx, ucMigrate, batches)
+	}
+	return r.runWithDump(ctx, r.applyMigrations(ucMigrate, migrationsToMigrate))
+}
+
+// Returns the migrations applied by Up in the order of applying. If Up has returned an error,
+// only the migrations that remain applied are returned, for example the versions committed with transaction-per-version.
+func (r *Runner) AppliedMigrations() []domain.AppliedMigration {
+	return r.appliedMigrations
+}
+
+// Checks that the files of applied migrations have not been changed after applying.
+// If they have been changed, they are returned together with domain.ErrChecksumMismatch.
+func (r *Runner) Verify(ctx context.Context) ([]domain.ChecksumMismatch, error) {
+	isInitModeON, currentMigration, err := r.currentMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.isLegacyTrackingON {
+		return nil, fmt.Errorf("checksums are stored only in the history table, the verification is not possible in legacy mode")
+	}
+
+	mismatches, err := r.verifyAppliedMigrations(ctx, isInitModeON, currentMigration)
+	if err != nil {
+		return nil, err
+	}
+	if len(mismatches) != 0 {
+		showChecksumMismatches(r.printer, mismatches)
+		return mismatches, domain.WithKind(domain.ErrChecksumMismatch,
+			fmt.Errorf("the files of %d applied migrations do not match the checksums", len(mismatches)))
+	}
+	r.printer.Println("The files of the applied migrations match the checksums")
+	return nil, nil
+}
+
+// Reverts the migrations from the current one to the one specified in -versiondb and -migration
+func (r *Runner) Rollback(ctx context.Context) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	isInitModeON, currentMigration, err := r.currentMigration(ctx)
+	if err != nil {
+		return err
+	}
+	if isInitModeON {
+		return domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("there are no applied migrations to roll back"))
+	}
+	if r.isLegacyTrackingON {
+		if err := r.checkUpdateCurrentMigrationFile(); err != nil {
+			return err
+		}
+	}
+
+	targetVersion, targetName, err := determinationTargetToRollback(r.cfg.StringVersionDb, r.cfg.StringNameMigration, currentMigration)
+	if err != nil {
+		return err
+	}
+	var repoHistory usecase.HistoryRepoForRollback
+	if !r.isLegacyTrackingON {
+		repoHistory = migration_postgres.NewHistoryPostgresRepo(r.conn, r.cfg.HistoryTable, r.cfg.App.Version)
+	}
+	ucRollback := usecase.NewRollbackUseCase(r.repoMigrationDisk, r.repoMigrationPostgres, repoHistory, r.printer)
+	migrationsToRollback, targetMigration, err := ucRollback.GetMigrationsToRollback(ctx, currentMigration, targetVersion, targetName)
+	if err != nil {
+		return domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("error when determining migrations to roll back: %w", err))
+	}
+	if len(migrationsToRollback) == 0 {
+		r.printer.Println("No migrations to roll back")
+		return nil
+	}
+	showMigrationsToRollback(r.printer, migrationsToRollback, targetMigration)
+	if r.confirm != nil {
+		if err := r.confirm(fmt.Sprintf("roll back the migrations in the %s database", r.cfg.DbName)); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.RollbackStrategy == config.RollbackDump {
+		return r.runWithDump(ctx, r.rollbackMigrations(ucRollback, migrationsToRollback, targetMigration))
+	}
+	return r.rollbackInTransactions(ctx, ucRollback, migrationsToRollback, targetMigration)
+}
+
+// Restores the database from the dump specified in -dump. The connection is needed only to determine the server version,
+// it is closed before the restore. In a protected profile the restore must be confirmed.
+func (r *Runner) Restore(ctx context.Context) error {
+	ucDump, err := r.newDumpUseCase()
+	if err != nil {
+		return err
+	}
+	dump, err := domain.NewDump(r.cfg.PathToDump)
+	if err != nil {
+		return fmt.Errorf("error when creating the dump: %w", err)
+	}
+
+	if r.confirm != nil {
+		r.printer.Printf("The %s database will be dropped and recreated from %s\n", r.cfg.DbName, dump.Path())
+		if err := r.confirm(fmt.Sprintf("restore the %s database from the dump", r.cfg.DbName)); err != nil {
+			return err
+		}
+	}
+
+	r.conn.Close(ctx)
+	if err := ucDump.RestoreDatabaseFromDump(ctx, dump); err != nil {
+		return domain.WithKind(domain.ErrRestoreFailed,
+			fmt.Errorf("error when restoring database from dump: %s: %w", err, ucDump.GetErrorForBadRestore(dump)))
+	}
+	return nil
+}
+
+// Transfers the current migration from utils/GetCurrentVersion.sql to the empty history table
+func (r *Runner) ImportLegacy(ctx context.Context) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	repoLegacy := migration_postgres.NewLegacyPostgresRepo(r.conn, nil, r.ucFileReader.GetSqlFromGetCurrentMigrationFile, nil)
+	ucLegacyMigrationCurrent := usecase.NewMigrationCurrentUseCase(repoLegacy, r.printer)
+	legacyCurrentMigration, err := ucLegacyMigrationCurrent.GetCurrentMigration(ctx)
+	if err != nil {
+		return fmt.Errorf("error when retrieving the current migration from %s: %w", r.ucFileReader.ShortPathToGetCurrentMigrationFile, err)
+	}
+
+	repoHistory := migration_postgres.NewHistoryPostgresRepo(r.conn, r.cfg.HistoryTable, r.cfg.App.Version)
+	ucImportLegacy := usecase.NewImportLegacyUseCase(repoHistory, r.printer)
+	if err := ucImportLegacy.Import(ctx, legacyCurrentMigration); err != nil {
+		return fmt.Errorf("error when importing the current migration into %s: %w", r.cfg.HistoryTable, err)
+	}
+	r.printer.Printf("The %s %s migration has been imported into %s\n",
+		legacyCurrentMigration.VersionDb.String(), legacyCurrentMigration.Name, r.cfg.HistoryTable)
+	return nil
+}
+
+// Returns what was applied, reverted or changed by hand, when and by whom, filtered by -from, -to, -since and -until.
+// The database is not changed.
+func (r *Runner) History(ctx context.Context) ([]domain.HistoryRecord, error) {
+	if r.isLegacyTrackingON {
+		return nil, domain.WithKind(domain.ErrInvalidParameters,
+			fmt.Errorf("the history is recorded only in the history table, the current migration is tracked with utils/*.sql, use -tracking=history"))
+	}
+	filter, err := newHistoryFilter(r.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	repoHistory := migration_postgres.NewHistoryPostgresRepo(r.conn, r.cfg.HistoryTable, r.cfg.App.Version)
+	ucHistory := usecase.NewHistoryUseCase(repoHistory, r.printer)
+	records, err := ucHistory.GetHistory(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading the history from %s: %w", r.cfg.HistoryTable, err)
+	}
+	showHistory(r.printer, records)
+	return records, nil
+}
+
+// Records the migration in -versiondb and -migration as the current one without executing anything.
+// Only for the history table and only if it has no rows: the database existed before dbupdater.
+func (r *Runner) Baseline(ctx context.Context) (*domain.Migration, error) {
+	if r.isLegacyTrackingON {
+		return nil, domain.WithKind(domain.ErrInvalidParameters,
+			fmt.Errorf("the baseline is recorded only in the history table, the current migration is tracked with utils/*.sql, use -tracking=history"))
+	}
+	version, err := domain.NewVersionDb(r.cfg.StringVersionDb)
+	if err != nil {
+		return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("wrong version in -versiondb: %w", err))
+	}
+
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	repoHistory := migration_postgres.NewHistoryPostgresRepo(r.conn, r.cfg.HistoryTable, r.cfg.App.Version)
+	ucBaseline := usecase.NewBaselineUseCase(repoHistory, r.printer)
+	ucMigrations := usecase.NewMigrationsUseCase(r.repoMigrationDisk, r.printer)
+	baselineMigration, err := ucMigrations.GetMigration(ctx, version, r.cfg.StringNameMigration)
+	if err != nil {
+		return nil, err
+	}
+	if r.confirm != nil {
+		showBaseline(r.printer, baselineMigration)
+		if err := r.confirm(fmt.Sprintf("record the baseline in the %s database", r.cfg.DbName)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ucBaseline.Baseline(ctx, baselineMigration); err != nil {
+		return nil, fmt.Errorf("error when recording the baseline in %s: %w", r.cfg.HistoryTable, err)
+	}
+	r.printer.Printf("The %s %s migration has been recorded in %s as the baseline, "+
+		"it and the migrations before it are considered applied\n",
+		baselineMigration.VersionDb.String(), baselineMigration.Name, r.cfg.HistoryTable)
+	return baselineMigration, nil
+}
+
+// Records the migrations from the current one to the one specified in -versiondb and -migration as applied
+// without executing them, for example after they have been applied by hand. The files are checked by verify like after up.
+func (r *Runner) MarkApplied(ctx context.Context) ([]domain.ManualChange, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	currentMigration, unappliedMigrations, err := r.unappliedMigrationsForManualChange(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(unappliedMigrations) == 0 {
+		return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("there are no migrations to mark as applied"))
+	}
+	lastMigration, err := determinationLastMigrationToMigrate(r.cfg.StringVersionDb, r.cfg.StringNameMigration,
+		currentMigration, unappliedMigrations)
+	if err != nil {
+		return nil, err
+	}
+	migrationsToMark, err := getMigrationGroupsAndMigrationsBeforeMigration(unappliedMigrations, lastMigration)
+	if err != nil {
+		return nil, err
+	}
+
+	ucMigrate := usecase.NewMigrateUseCase(r.repoMigrationDisk, r.repoMigrationPostgres, r.printer)
+	changes := make([]domain.ManualChange, 0)
+	for _, mg := range migrationsToMark {
+		for i := range mg.Migrations {
+			migration := mg.Migrations[i]
+			sql, err := ucMigrate.GetSql(ctx, &migration)
+			if err != nil {
+				return nil, err
+			}
+			change := domain.NewManualChange(domain.ManualChangeMarkApplied, &migration, r.cfg.Reason, helper.CurrentOsUser())
+			change.Checksum = domain.CalculateChecksum(sql)
+			changes = append(changes, *change)
+		}
+	}
+	if err := r.recordManualChanges(ctx, changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Records the next migration as skipped, it is not executed and up continues after it.
+// If -versiondb or -migration is specified, it must be the next migration.
+func (r *Runner) Skip(ctx context.Context) (*domain.ManualChange, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	currentMigration, unappliedMigrations, err := r.unappliedMigrationsForManualChange(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(unappliedMigrations) == 0 {
+		return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("there are no migrations to skip"))
+	}
+	nextMigration := &unappliedMigrations[0].Migrations[0]
+	if r.cfg.StringVersionDb != "" || r.cfg.StringNameMigration != "" {
+		targetMigration, err := r.migrationOnDisk(ctx, currentMigration)
+		if err != nil {
+			return nil, err
+		}
+		if !targetMigration.IsEqual(nextMigration) {
+			return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("only the next migration %s %s can be skipped",
+				nextMigration.VersionDb.String(), nextMigration.Name))
+		}
+	}
+
+	change := domain.NewManualChange(domain.ManualChangeSkip, nextMigration, r.cfg.Reason, helper.CurrentOsUser())
+	if err := r.recordManualChanges(ctx, []domain.ManualChange{*change}); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// Sets the migration specified in -versiondb and -migration as the current one without executing anything,
+// for example after the database has been fixed by hand following a failed restore. The current migration
+// recorded before can be wrong or unreadable.
+func (r *Runner) Repair(ctx context.Context) (*domain.ManualChange, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var currentMigration *domain.Migration
+	isInitModeON, err := isInitMode(ctx, r.isLegacyTrackingON, r.ucMigrationCurrent)
+	if err == nil && !isInitModeON {
+		currentMigration, err = r.ucMigrationCurrent.GetCurrentMigration(ctx)
+	}
+	switch {
+	case err != nil:
+		r.printer.Printf("WARNING. The current migration cannot be read: %s\n", err)
+	case currentMigration != nil:
+		showCurrentMigration(r.printer, currentMigration)
+	}
+
+	targetMigration, err := r.migrationOnDisk(ctx, currentMigration)
+	if err != nil {
+		return nil, err
+	}
+	change := domain.NewManualChange(domain.ManualChangeRepair, targetMigration, r.cfg.Reason, helper.CurrentOsUser())
+	if err := r.recordManualChanges(ctx, []domain.ManualChange{*change}); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// Returns the current migration and the migrations after it. The current migration must be in -migrations.
+func (r *Runner) unappliedMigrationsForManualChange(ctx context.Context) (*domain.Migration, []domain.MigrationGroup, error) {
+	isInitModeON, currentMigration, err := r.currentMigration(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ucMigrations := usecase.NewMigrationsUseCase(r.repoMigrationDisk, r.printer)
+	unappliedMigrations, err := ucMigrations.GetUnappliedSortedMigrations(ctx, isInitModeON, currentMigration)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error when receiving unapplied migrations: %w", err)
+	}
+	return currentMigration, unappliedMigrations, nil
+}
+
+// Returns the migration specified in -versiondb and -migration, it must be in -migrations.
+// If -versiondb is not specified, the migration is searched in the version of the current migration.
+func (r *Runner) migrationOnDisk(ctx context.Context, currentMigration *domain.Migration) (*domain.Migration, error) {
+	var version *domain.VersionDb
+	switch {
+	case r.cfg.StringVersionDb != "":
+		var err error
+		version, err = domain.NewVersionDb(r.cfg.StringVersionDb)
+		if err != nil {
+			return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("wrong version in -versiondb: %w", err))
+		}
+	case currentMigration != nil:
+		version = currentMigration.VersionDb
+	default:
+		return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("specify the version of the migration in the -versiondb parameter"))
+	}
+
+	ucMigrations := usecase.NewMigrationsUseCase(r.repoMigrationDisk, r.printer)
+	return ucMigrations.GetMigration(ctx, version, r.cfg.StringNameMigration)
+}
+
+// Shows the changes, asks for the confirmation and records them with the reason and the user
+func (r *Runner) recordManualChanges(ctx context.Context, changes []domain.ManualChange) error {
+	if r.isLegacyTrackingON {
+		if err := r.checkUpdateCurrentMigrationFile(); err != nil {
+			return err
+		}
+	}
+	showManualChanges(r.printer, changes)
+	if r.confirm != nil {
+		if err := r.confirm(fmt.Sprintf("change the current migration of the %s database", r.cfg.DbName)); err != nil {
+			return err
+		}
+	}
+
+	if err := r.ucMigrationCurrent.RecordManualChanges(ctx, changes); err != nil {
+		return fmt.Errorf("error when recording the changes in %s: %w", r.trackingTable(), err)
+	}
+	if r.isLegacyTrackingON {
+		r.printer.Printf("WARNING. The reason and the user are not recorded, the current migration is tracked with %s\n", r.trackingTable())
+	}
+	last := changes[len(changes)-1].Migration
+	r.printer.Printf("The current migration is %s %s\n", last.VersionDb.String(), last.Name)
+	return nil
+}
+
+// Takes the advisory lock before the current migration is read, so another dbupdater cannot apply the same migrations.
+// The lock is held until the returned function is called. If the connection has been closed, the lock has already been released.
+func (r *Runner) lock(ctx context.Context) (func(), error) {
+	lock := domain.NewLock(r.cfg.DbName, r.trackingTable())
+
+	ucLock := usecase.NewLockUseCase(r.repoLock, r.printer)
+	if err := ucLock.Lock(ctx, lock, r.cfg.LockTimeout); err != nil {
+		return nil, err
+	}
+	return func() {
+		if r.conn.IsClosed() {
+			return
+		}
+		if err := ucLock.Unlock(ctx, lock); err != nil {
+			r.printer.Errorf("%s\n", err)
+		}
+	}, nil
+}
+
+// Closing the connection before the restore releases the lock taken by lock. So that another dbupdater does not change
+// the database while it is restored, the lock is taken in the database 'postgres' before the connection is closed
+// and held until the returned function is called.
+func (r *Runner) lockForRestore(ctx context.Context) (func(), error) {
+	entry := r.cfg.DbEntry
+	entry.DbName = "postgres"
+	conn, err := helper.OpenConnect(ctx, &entry, false)
+	if err != nil {
+		return nil, fmt.Errorf("error when connecting to the database 'postgres' to hold the lock: %w", err)
+	}
+
+	lock := domain.NewLock(r.cfg.DbName, r.trackingTable())
+	ucLock := usecase.NewLockUseCase(migration_postgres.NewLockPostgresRepo(conn), r.printer)
+	if err := ucLock.LockForRestore(ctx, lock); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+	return func() {
+		conn.Close(ctx)
+	}, nil
+}
+
+// Returns where the current migration is stored: the history table or, in legacy mode, the file that updates it
+func (r *Runner) trackingTable() string {
+	if r.isLegacyTrackingON {
+		return r.ucFileReader.ShortPathToUpdateCurrentMigrationFile
+	}
+	return r.cfg.HistoryTable
+}
+
+// Determines and shows the current migration. In initialization mode it is the initial migration.
+func (r *Runner) currentMigration(ctx context.Context) (bool, *domain.Migration, error) {
+	isInitModeON, err := isInitMode(ctx, r.isLegacyTrackingON, r.ucMigrationCurrent)
+	if err != nil {
+		return false, nil, err
+	}
+	if isInitModeON {
+		r.printer.Printf("Initialization mode - ON\n"+
+			"Migrations will be applied starting from version %s\n", r.ucInitMigration.GetInitMigration().VersionDb.String())
+	}
+
+	currentMigration, err := determinationCurrentMigration(ctx, isInitModeON, r.ucInitMigration, r.ucMigrationCurrent)
+	if err != nil {
+		return false, nil, err
+	}
+	showCurrentMigration(r.printer, currentMigration)
+	return isInitModeON, currentMigration, nil
+}
+
+// Returns the migrations up to the one specified in -versiondb and -migration.
+// Checks that the current migration can be updated and that the files of applied migrations have not been changed.
+func (r *Runner) getMigrationsToMigrate(ctx context.Context, status *Status) ([]domain.MigrationGroup, error) {
+	lastMigrationToMigrate, err := determinationLastMigrationToMigrate(r.cfg.StringVersionDb, r.cfg.StringNameMigration,
+		status.CurrentMigration, status.UnappliedMigrations)
+	if err != nil {
+		return nil, err
+	}
+	migrationsToMigrate, err := getMigrationGroupsAndMigrationsBeforeMigration(status.UnappliedMigrations, lastMigrationToMigrate)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkBeforeMigrate(ctx, status); err != nil {
+		return nil, err
+	}
+	return migrationsToMigrate, nil
+}
+
+// Returns the migrations of the plan saved in -plan and their sql text checked against the plan.
+// The current migration of the database and the migration files must be the same as when planning,
+// the files of applied migrations are checked in the same way as without the plan.
+func (r *Runner) getMigrationsFromSavedPlan(ctx context.Context, status *Status) ([]domain.MigrationGroup, *usecase.VerifiedSql, error) {
+	ucSavedPlan := usecase.NewSavedPlanUseCase(plan_file.NewPlanFileRepo(), r.repoMigrationDisk, r.printer)
+	plan, err := ucSavedPlan.Load(ctx, r.cfg.PathToPlan)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ucSavedPlan.CheckCurrentMigration(plan, status.IsInitMode, status.CurrentMigration); err != nil {
+		return nil, nil, err
+	}
+	if len(plan.Migrations) == 0 {
+		return nil, nil, nil
+	}
+
+	migrationsToMigrate, err := getMigrationGroupsAndMigrationsBeforeMigration(status.UnappliedMigrations, plan.LastMigration())
+	if err != nil {
+		// The last migration of the plan has been deleted, all differences are shown by the check
+		migrationsToMigrate = status.UnappliedMigrations
+	}
+	verifiedSql, err := ucSavedPlan.CheckMigrations(ctx, plan, migrationsToMigrate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.checkBeforeMigrate(ctx, status); err != nil {
+		return nil, nil, err
+	}
+	return migrationsToMigrate, verifiedSql, nil
+}
+
+// With -out saves the migrations to apply together with the checksums of their files and the current migration
+func (r *Runner) savePlan(ctx context.Context, status *Status, migrationsToMigrate []domain.MigrationGroup) error {
+	if r.cfg.PathToPlanOut == "" {
+		return nil
+	}
+	ucSavedPlan := usecase.NewSavedPlanUseCase(plan_file.NewPlanFileRepo(), r.repoMigrationDisk, r.printer)
+	return ucSavedPlan.Save(ctx, r.cfg.PathToPlanOut, status.IsInitMode, status.CurrentMigration, migrationsToMigrate)
+}
+
+// Checks that the current migration can be updated and that the files of applied migrations have not been changed
+func (r *Runner) checkBeforeMigrate(ctx context.Context, status *Status) error {
+	if r.isLegacyTrackingON {
+		return r.checkUpdateCurrentMigrationFile()
+	}
+
+	mismatches, err := r.verifyAppliedMigrations(ctx, status.IsInitMode, status.CurrentMigration)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) != 0 {
+		showChecksumMismatches(r.printer, mismatches)
+		if !r.cfg.IsIgnoreChecksums {
+			return domain.WithKind(domain.ErrChecksumMismatch,
+				fmt.Errorf("the files of applied migrations have been changed. Restore them or specify -ignorechecksums to apply anyway"))
+		}
+		r.printer.Println("WARNING. The changed files of applied migrations are ignored because of -ignorechecksums.")
+	}
+	return nil
+}
+
+// Returns the applied migrations whose files have been changed or deleted after applying
+func (r *Runner) verifyAppliedMigrations(ctx context.Context, isInitModeON bool, currentMigration *domain.Migration,
+) ([]domain.ChecksumMismatch, error) {
+	if isInitModeON {
+		return nil, nil
+	}
+
+	repoHistory := migration_postgres.NewHistoryPostgresRepo(r.conn, r.cfg.HistoryTable, r.cfg.App.Version)
+	ucVerify := usecase.NewVerifyUseCase(repoHistory, r.repoMigrationDisk, r.printer)
+	mismatches, err := ucVerify.Verify(ctx, currentMigration)
+	if err != nil {
+		return nil, fmt.Errorf("error when checking the checksums of applied migrations: %w", err)
+	}
+	return mismatches, nil
+}
+
+// In legacy mode, the current migration cannot be changed without utils/UpdateCurrentVersion.sql
+func (r *Runner) checkUpdateCurrentMigrationFile() error {
+	if _, err := r.ucFileReader.GetSqlFromUpdateCurrentMigrationFile(); err != nil {
+		return fmt.Errorf("error when retrieving sql text from %s: %w", r.ucFileReader.ShortPathToUpdateCurrentMigrationFile, err)
+	}
+	return nil
+}
+
+// Changes the database and updates the current migration. Returns an error if the changes have not been made.
+type changeDatabase func(ctx context.Context) error
+
+// Returns the change that applies the migrations and updates the current migration
+func (r *Runner) applyMigrations(ucMigrate *usecase.MigrateUseCase, migrationsToMigrate []domain.MigrationGroup) changeDatabase {
+	return func(ctx context.Context) error {
+		appliedMigrations, err := ucMigrate.Migrate(ctx, migrationsToMigrate, r.cfg.IsByStatements)
+		if err != nil {
+			return fmt.Errorf("error when applying migrations: %w", err)
+		}
+		if err := r.ucMigrationCurrent.UpdateCurrentMigration(ctx, appliedMigrations); err != nil {
+			return fmt.Errorf("error when updating the current migration: %w", err)
+		}
+		r.appliedMigrations = append(r.appliedMigrations, appliedMigrations...)
+		return nil
+	}
+}
+
+// Returns the change that executes the down scripts and updates the current migration
+func (r *Runner) rollbackMigrations(ucRollback *usecase.RollbackUseCase, migrationsToRollback []domain.MigrationGroup,
+	targetMigration *domain.Migration,
+) changeDatabase {
+	return func(ctx context.Context) error {
+		revertedMigrations, err := ucRollback.Rollback(ctx, migrationsToRollback, targetMigration)
+		if err != nil {
+			return fmt.Errorf("error when rolling back migrations: %w", err)
+		}
+		if err := r.ucMigrationCurrent.RevertCurrentMigration(ctx, revertedMigrations); err != nil {
+			return fmt.Errorf("error when updating the current migration: %w", err)
+		}
+		return nil
+	}
+}
+
+// Before changing the database, a dump is created. If an error occurs, the database is restored from the dump.
+// Returns an error if the changes have not been made, in this case the connection is closed.
+func (r *Runner) runWithDump(ctx context.Context, change changeDatabase) error {
+	ucDump, err := r.newDumpUseCase()
+	if err != nil {
+		return err
+	}
+
+	newDump, err := ucDump.Create(ctx)
+	if err != nil {
+		return fmt.Errorf("error when creating a new dump: %w", err)
+	}
+
+	if r.isHandleInterrupt {
+		stopCloseHandler := setupCloseHandler(ucDump, newDump, r.conn, ctx)
+		defer stopCloseHandler()
+	}
+
+	numberOfApplied := len(r.appliedMigrations)
+	if err := change(ctx); err != nil {
+		r.appliedMigrations = r.appliedMigrations[:numberOfApplied]
+		unlockRestore, errFromLock := r.lockForRestore(ctx)
+		if errFromLock != nil {
+			return domain.WithKind(domain.ErrRestoreFailed, fmt.Errorf("the database has not been restored from the dump: %s: %w",
+				errFromLock, ucDump.GetErrorForBadRestore(newDump)))
+		}
+		defer unlockRestore()
+		r.conn.Close(ctx)
+		if errFromRestore := ucDump.RestoreDatabaseFromDumpAndDeleteDump(ctx, newDump); errFromRestore != nil {
+			return domain.WithKind(domain.ErrRestoreFailed, fmt.Errorf("error when restoring database from dump: %s: %w",
+				errFromRestore, ucDump.GetErrorForBadRestore(newDump)))
+		}
+		return domain.WithKind(r.kindOfFailedChange(), fmt.Errorf("the database has been restored from the dump: %w", err))
+	}
+
+	if err := os.Remove(newDump.Path()); err != nil {
+		r.printer.Printf("Error when deleting dump file: %s\n", err)
+		return nil
+	}
+	r.printer.ShowIfVerbose("Dump deleted.")
+	return nil
+}
+
+// Changes the database in a transaction. Returns an error if the transaction has been rolled back.
+func (r *Runner) runInTransaction(ctx context.Context, ucTransaction *usecase.TransactionUseCase, change changeDatabase) error {
+	if r.isHandleInterrupt {
+		exitCode := ExitApplyFailed
+		if r.isPartlyChanged {
+			exitCode = ExitPartiallyApplied
+		}
+		stopCloseHandler := setupCloseHandlerForTransaction(r.conn, ctx, exitCode)
+		defer stopCloseHandler()
+	}
+
+	if err := ucTransaction.Begin(ctx); err != nil {
+		return fmt.Errorf("error when beginning a transaction: %w", err)
+	}
+
+	numberOfApplied := len(r.appliedMigrations)
+	if err := change(ctx); err != nil {
+		r.appliedMigrations = r.appliedMigrations[:numberOfApplied]
+		if errFromRollback := ucTransaction.Rollback(ctx); errFromRollback != nil {
+			// It is not known whether the changes have been rolled back, the database must be checked
+			return domain.WithKind(domain.ErrRestoreFailed,
+				fmt.Errorf("error when rolling back the transaction, check the state of the database: %s: %w", errFromRollback, err))
+		}
+		return domain.WithKind(r.kindOfFailedChange(), fmt.Errorf("the transaction has been rolled back: %w", err))
+	}
+
+	if err := ucTransaction.Commit(ctx); err != nil {
+		r.appliedMigrations = r.appliedMigrations[:numberOfApplied]
+		// The server rolls back the transaction if it refuses to commit it. Without its answer the result is not known.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return domain.WithKind(r.kindOfFailedChange(), fmt.Errorf("error when committing the transaction, it has been rolled back: %w", err))
+		}
+		return domain.WithKind(domain.ErrRestoreFailed,
+			fmt.Errorf("error when committing the transaction, check the state of the database: %w", err))
+	}
+	return nil
+}
+
+// The failed change has been returned to its previous state. If the changes committed before it remain,
+// the database is not in the state before the command.
+func (r *Runner) kindOfFailedChange() error {
+	if r.isPartlyChanged {
+		return domain.ErrPartiallyApplied
+	}
+	return domain.ErrApplyFailed
+}
+
+// Each batch of migration groups is applied together with the update of the current migration in its own transaction.
+// If an error occurs, the transaction of the batch is rolled back, the batches applied before it are kept.
+// Migrations that cannot be executed inside a transaction are applied separately, a dump is created before them.
+func (r *Runner) migrateInTransactions(ctx context.Context, ucMigrate *usecase.MigrateUseCase, batches [][]domain.MigrationGroup) error {
+	ucTransaction := usecase.NewTransactionUseCase(r.repoMigrationPostgres, r.printer)
+
+	var lastCommittedMigration *domain.Migration
+	showKeptMigrations := func() {
+		if lastCommittedMigration != nil {
+			r.printer.Printf("The migrations up to %s %s inclusive remain applied.\n",
+				lastCommittedMigration.VersionDb.String(), lastCommittedMigration.Name)
+		}
+	}
+
+	for _, batch := range batches {
+		for _, part := range splitByTransactionMode(batch, isMigrationNoTransaction) {
+			change := r.applyMigrations(ucMigrate, part.migrationGroups)
+			if part.isNoTransaction {
+				migration := part.migrationGroups[0].Migrations[0]
+				r.printer.Printf("The %s %s migration cannot be executed inside a transaction, a dump is created before it.\n",
+					migration.VersionDb.String(), migration.Name)
+				if err := r.runWithDump(ctx, change); err != nil {
+					showKeptMigrations()
+					return err
+				}
+				lastCommittedMigration = &migration
+				r.isPartlyChanged = true
+				continue
+			}
+
+			if err := r.runInTransaction(ctx, ucTransaction, change); err != nil {
+				showKeptMigrations()
+				return err
+			}
+			lastGroup := part.migrationGroups[len(part.migrationGroups)-1]
+			lastCommittedMigration = &lastGroup.Migrations[len(lastGroup.Migrations)-1]
+			r.isPartlyChanged = true
+		}
+	}
+	return nil
+}
+
+// The down scripts are executed together with the update of the current migration in one transaction.
+// The down scripts that cannot be executed inside a transaction are executed separately, a dump is created before them.
+// If an error occurs, the migrations reverted before the failed part remain reverted.
+func (r *Runner) rollbackInTransactions(ctx context.Context, ucRollback *usecase.RollbackUseCase,
+	migrationsToRollback []domain.MigrationGroup, targetMigration *domain.Migration,
+) error {
+	ucTransaction := usecase.NewTransactionUseCase(r.repoMigrationPostgres, r.printer)
+
+	var lastCurrentMigration *domain.Migration
+	showKeptMigrations := func() {
+		if lastCurrentMigration != nil {
+			r.printer.Printf("The migrations after %s %s remain reverted.\n",
+				lastCurrentMigration.VersionDb.String(), lastCurrentMigration.Name)
+		}
+	}
+
+	parts := splitByTransactionMode(migrationsToRollback, isDownNoTransaction)
+	for i, part := range parts {
+		// The first migration of the next part becomes current after reverting the part
+		currentAfterPart := targetMigration
+		if i+1 < len(parts) {
+			currentAfterPart = &parts[i+1].migrationGroups[0].Migrations[0]
+		}
+
+		change := r.rollbackMigrations(ucRollback, part.migrationGroups, currentAfterPart)
+		if part.isNoTransaction {
+			migration := part.migrationGroups[0].Migrations[0]
+			r.printer.Printf("The down script of the %s %s migration cannot be executed inside a transaction, a dump is created before it.\n",
+				migration.VersionDb.String(), migration.Name)
+			if err := r.runWithDump(ctx, change); err != nil {
+				showKeptMigrations()
+				return err
+			}
+		} else if err := r.runInTransaction(ctx, ucTransaction, change); err != nil {
+			showKeptMigrations()
+			return err
+		}
+		lastCurrentMigration = currentAfterPart
+		r.isPartlyChanged = true
+	}
+	return nil
+}
+
+func (r *Runner) newDumpUseCase() (*usecase.DumpUseCase, error) {
+	infraDumpPostgres, err := dump_postgres.NewDumpPostgres(r.cfg.DbEntry, r.cfg.DumpUtilities, helper.GetServerVersion(r.conn))
+	if err != nil {
+		return nil, fmt.Errorf("error when creating infraDumpPostgres: %w", err)
+	}
+	ucDump, err := usecase.NewDumpUseCase(infraDumpPostgres, r.printer)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating ucDump: %w", err)
+	}
+	return ucDump, nil
+}
+
+// Creates a repository that stores the current migration in the history table or, in legacy mode, with user queries
+func newRepoMigrationCurrent(cfg *config.Config, conn *pgx.Conn, ucFileReader *usecase.FileReaderUseCase,
+	isLegacyTrackingON bool,
+) usecase.MigrationRepoForMigrationCurrent {
+	if !isLegacyTrackingON {
+		return migration_postgres.NewHistoryPostgresRepo(conn, cfg.HistoryTable, cfg.App.Version)
+	}
+
+	var sqlForCheckMigration migration_postgres.SqlSource
+	if ucFileReader.IsExistHasCurrentMigrationFile() {
+		sqlForCheckMigration = ucFileReader.GetSqlFromHasCurrentMigrationFile
+	}
+	return migration_postgres.NewLegacyPostgresRepo(conn, sqlForCheckMigration,
+		ucFileReader.GetSqlFromGetCurrentMigrationFile, ucFileReader.GetSqlFromUpdateCurrentMigrationFile)
+}
+
+// The migrations are read from cfg.MigrationsFS, otherwise from the directory or the archive in cfg.PathToMigrations.
+// The restore command does not need migrations, an empty file system is returned for it.
+func openMigrationsFS(cfg *config.Config) (fs.FS, func() error, error) {
+	if cfg.MigrationsFS != nil {
+		return cfg.MigrationsFS, func() error { return nil }, nil
+	}
+	if cfg.PathToMigrations == "" {
+		return emptyFS{}, func() error { return nil }, nil
+	}
+	return helper.OpenMigrationsFS(cfg.PathToMigrations)
+}
+
+type emptyFS struct{}
+
+func (emptyFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// The connection parameters are not checked, the missing ones are taken from the environment in the same way as libpq does
+func checkRequiredParameters(cfg *config.Config) error {
+	if cfg.Command == config.CommandBaseline && cfg.StringVersionDb == "" {
+		return domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("specify the version of the baseline in the -versiondb parameter"))
+	}
+	switch cfg.Command {
+	case config.CommandMarkApplied, config.CommandSkip, config.CommandRepair:
+		if cfg.Reason == "" {
+			return domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("specify why the current migration is changed in the -reason parameter"))
+		}
+		// Skip without a target skips the next migration
+		if cfg.Command != config.CommandSkip && cfg.StringVersionDb == "" && cfg.StringNameMigration == "" {
+			return domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("specify the migration in the -versiondb and -migration parameters"))
+		}
+	}
+	if cfg.Command == config.CommandRestore {
+		if cfg.PathToDump == "" {
+			return domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("specify the path to the dump in the -dump parameter"))
+		}
+		return nil
+	}
+	// The history is read only from the database
+	if cfg.Command == config.CommandHistory {
+		return nil
+	}
+	if cfg.PathToMigrations == "" && cfg.MigrationsFS == nil {
+		return domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("To view the current version of the database, the last applied migration, "+
+			"apply new migrations, specify the path to the directory with migration scripts in the -migrations parameter"))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package core
+
+// The file is used to describe the commands that create migration files. They do not use the database.
+// It is forbidden to call infrastructure methods.
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/internal/usecase"
+
+	"github.com/awsoremod/DBUpdater/internal/infrastructure/repo/migration_disk"
+)
+
+// Creates the next migration file of the version in -versiondb, the latest version if it is not specified.
+// Returns the paths of the created files.
+func NewMigration(ctx context.Context, cfg *config.Config, printer *helper.Printer) ([]string, error) {
+	ucScaffold, err := newScaffoldUseCase(cfg, printer)
+	if err != nil {
+		return nil, err
+	}
+
+	var version *domain.VersionDb
+	if cfg.StringVersionDb != "" {
+		version, err = domain.NewVersionDb(cfg.StringVersionDb)
+		if err != nil {
+			return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("wrong version in -versiondb: %w", err))
+		}
+	}
+	return ucScaffold.NewMigration(ctx, version, cfg.NewMigrationName, cfg.IsCreateDown, cfg.IsNoTransaction, cfg.PathToTemplate)
+}
+
+// Creates the directory of the version following the latest version, v0.0.0 if there are no versions.
+// Returns the path of the created directory.
+func NewVersion(ctx context.Context, cfg *config.Config, printer *helper.Printer) (string, error) {
+	ucScaffold, err := newScaffoldUseCase(cfg, printer)
+	if err != nil {
+		return "", err
+	}
+
+	next := (*domain.VersionDb).NextPatch
+	switch cfg.Bump {
+	case config.BumpMinor:
+		next = (*domain.VersionDb).NextMinor
+	case config.BumpMajor:
+		next = (*domain.VersionDb).NextMajor
+	}
+	return ucScaffold.NewVersion(ctx, next)
+}
+
+// The files are created only in a directory, not in an archive or embedded files
+func newScaffoldUseCase(cfg *config.Config, printer *helper.Printer) (*usecase.ScaffoldUseCase, error) {
+	if cfg.PathToMigrations == "" {
+		return nil, domain.WithKind(domain.ErrInvalidParameters,
+			fmt.Errorf("specify the path to the directory with migration scripts in the -migrations parameter"))
+	}
+	info, err := os.Stat(cfg.PathToMigrations)
+	if err != nil {
+		return nil, domain.WithKind(domain.ErrNotFound, fmt.Errorf("error when opening the migrations: %w", err))
+	}
+	if !info.IsDir() {
+		return nil, domain.WithKind(domain.ErrInvalidParameters,
+			fmt.Errorf("%s is not a directory, the files can be created only in a directory", cfg.PathToMigrations))
+	}
+
+	repoMigrationDisk := migration_disk.NewMigrationDiskRepoo(os.DirFS(cfg.PathToMigrations), printer)
+	repoMigrationDir := migration_disk.NewMigrationDirRepo(cfg.PathToMigrations)
+	return usecase.NewScaffoldUseCase(repoMigrationDisk, repoMigrationDir, printer), nil
+}
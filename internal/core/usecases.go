@@ -5,52 +5,61 @@ package core
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"time"
 
-	"dbupdater/internal/domain"
-	"dbupdater/internal/usecase"
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/internal/usecase"
 )
 
-func isInitMode(ucFileReader *usecase.FileReaderUseCase, ucMigrationCurrent *usecase.MigrationCurrentUseCase) bool {
-	isExistHasCurrentMigrationFile := ucFileReader.IsExistHasCurrentMigrationFile()
-	if !isExistHasCurrentMigrationFile {
+// In legacy mode, user queries from the utils directory are used to track the current migration
+func isLegacyTracking(trackingMode string, ucFileReader *usecase.FileReaderUseCase) bool {
+	switch trackingMode {
+	case config.TrackingLegacy:
+		return true
+	case config.TrackingHistory:
 		return false
 	}
-	ctx := context.Background()
+	return ucFileReader.IsExistGetCurrentMigrationFile() || ucFileReader.IsExistHasCurrentMigrationFile()
+}
 
-	sqlFromHasCurrentMigrationFile, err := ucFileReader.GetSqlFromHasCurrentMigrationFile()
-	if err != nil {
-		log.Fatalf("Error when retrieving sql text from %s: %s", ucFileReader.ShortPathToHasCurrentMigrationFile, err)
+// Initialization mode is on if there is no current migration in the database.
+// In legacy mode, an error when executing utils/HasCurrentVersion.sql also means that there is no current migration.
+func isInitMode(ctx context.Context, isLegacyTrackingON bool, ucMigrationCurrent *usecase.MigrationCurrentUseCase) (bool, error) {
+	isAvailableCurrentMigrationInDatabase, err := ucMigrationCurrent.HasCurrentMigration(ctx)
+	if err != nil && !isLegacyTrackingON {
+		return false, fmt.Errorf("error when checking the migration history: %w", err)
 	}
-	isAvailableCurrentMigrationInDatabase, err := ucMigrationCurrent.HasCurrentMigration(ctx, sqlFromHasCurrentMigrationFile)
 	if err != nil || !isAvailableCurrentMigrationInDatabase {
-		return true
+		return true, nil
 	}
-	return false
+	return false, nil
 }
 
-func determinationCurrentMigration(isInitMod bool, ucInitMigration *usecase.InitMigrationUseCase,
-	ucFileReader *usecase.FileReaderUseCase, ucMigrationCurrent *usecase.MigrationCurrentUseCase,
-) *domain.Migration {
+func determinationCurrentMigration(ctx context.Context, isInitMod bool, ucInitMigration *usecase.InitMigrationUseCase,
+	ucMigrationCurrent *usecase.MigrationCurrentUseCase,
+) (*domain.Migration, error) {
 	if isInitMod {
-		return ucInitMigration.GetInitMigration()
+		return ucInitMigration.GetInitMigration(), nil
 	}
-	ctx := context.Background()
 
-	sqlFromGetCurrentMigrationFile, err := ucFileReader.GetSqlFromGetCurrentMigrationFile()
+	currentMigration, err := ucMigrationCurrent.GetCurrentMigration(ctx)
 	if err != nil {
-		log.Fatalf("Error when retrieving sql text from %s: %s", ucFileReader.ShortPathToGetCurrentMigrationFile, err)
+		return nil, fmt.Errorf("error when retrieving the current database version and the last applied migration: %w", err)
 	}
-	currentMigration, err := ucMigrationCurrent.GetCurrentMigration(ctx, sqlFromGetCurrentMigrationFile)
-	if err != nil {
-		log.Fatalf("Error when retrieving the current database version and the last applied migration: %s", err)
-	}
-	return currentMigration
+	return currentMigration, nil
 }
 
+// If neither the version nor the migration is specified, the last of the unapplied migrations is returned
 func determinationLastMigrationToMigrate(stringVersionDb string, stringNameMigration string,
 	currentMigration *domain.Migration, unappliedMigrations []domain.MigrationGroup,
-) *domain.Migration {
+) (*domain.Migration, error) {
+	if stringVersionDb == "" && stringNameMigration == "" {
+		lastMigrationGroup := unappliedMigrations[len(unappliedMigrations)-1]
+		return &lastMigrationGroup.Migrations[len(lastMigrationGroup.Migrations)-1], nil
+	}
+
 	var err error
 	var versiondb *domain.VersionDb
 	if stringVersionDb == "" {
@@ -58,39 +67,61 @@ func determinationLastMigrationToMigrate(stringVersionDb string, stringNameMigra
 	} else {
 		versiondb, err = domain.NewVersionDb(stringVersionDb)
 		if err != nil {
-			log.Fatalf("wrong version in -versiondb: %s", err)
+			return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("wrong version in -versiondb: %w", err))
 		}
 	}
 
-	var lastMigrationToMigrate *domain.Migration
 	if stringNameMigration == "" {
 		for _, mg := range unappliedMigrations {
 			if mg.VersionDb.Equal(versiondb) {
-				lastMigrationToMigrate = &mg.Migrations[len(mg.Migrations)-1]
-				return lastMigrationToMigrate
+				return &mg.Migrations[len(mg.Migrations)-1], nil
 			}
 		}
-		log.Fatalf("The %s version is not in the list of migrations available for updating", versiondb.String())
-	} else {
-		lastMigrationToMigrate, err = domain.NewMigration(stringNameMigration, versiondb)
-		if err != nil {
-			log.Fatalf("Incorrect migration from -versiondb and -migration parameters: %s", err)
-		}
-		lastIndexMigrationGroup, lastIndexMigration := domain.IndicesMigrationInMigrationGroups(unappliedMigrations, lastMigrationToMigrate)
-		if lastIndexMigrationGroup == -1 || lastIndexMigration == -1 {
-			log.Fatalf("The %s %s migration is not in the list of migrations available for updating",
-				lastMigrationToMigrate.VersionDb.String(), lastMigrationToMigrate.Name)
-		}
+		return nil, domain.WithKind(domain.ErrInvalidTarget,
+			fmt.Errorf("The %s version is not in the list of migrations available for updating", versiondb.String()))
+	}
+
+	lastMigrationToMigrate, err := domain.NewMigration(stringNameMigration, versiondb)
+	if err != nil {
+		return nil, domain.WithKind(domain.ErrInvalidTarget,
+			fmt.Errorf("Incorrect migration from -versiondb and -migration parameters: %w", err))
+	}
+	lastIndexMigrationGroup, lastIndexMigration := domain.IndicesMigrationInMigrationGroups(unappliedMigrations, lastMigrationToMigrate)
+	if lastIndexMigrationGroup == -1 || lastIndexMigration == -1 {
+		return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("The %s %s migration is not in the list of migrations available for updating",
+			lastMigrationToMigrate.VersionDb.String(), lastMigrationToMigrate.Name))
 	}
-	return lastMigrationToMigrate
+	return lastMigrationToMigrate, nil
+}
+
+// Returns the version and the migration name to roll back to. If -versiondb is not specified, the current version is used.
+// If -migration is not specified, the name is empty, which means the last migration of the version.
+func determinationTargetToRollback(stringVersionDb string, stringNameMigration string,
+	currentMigration *domain.Migration,
+) (*domain.VersionDb, string, error) {
+	if stringVersionDb == "" && stringNameMigration == "" {
+		return nil, "", domain.WithKind(domain.ErrInvalidTarget,
+			fmt.Errorf("specify the version in -versiondb and/or the migration in -migration to roll back to"))
+	}
+	if stringVersionDb == "" {
+		return currentMigration.VersionDb, stringNameMigration, nil
+	}
+
+	versiondb, err := domain.NewVersionDb(stringVersionDb)
+	if err != nil {
+		return nil, "", domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("wrong version in -versiondb: %w", err))
+	}
+	return versiondb, stringNameMigration, nil
 }
 
 // Returns all migration groups with migrations before the specified migration
-func getMigrationGroupsAndMigrationsBeforeMigration(sortedMigrationGroups []domain.MigrationGroup, beforeThisMigration *domain.Migration) []domain.MigrationGroup {
+func getMigrationGroupsAndMigrationsBeforeMigration(sortedMigrationGroups []domain.MigrationGroup,
+	beforeThisMigration *domain.Migration,
+) ([]domain.MigrationGroup, error) {
 	lastIndexMigrationGroup, lastIndexMigration := domain.IndicesMigrationInMigrationGroups(sortedMigrationGroups, beforeThisMigration)
 	if lastIndexMigrationGroup == -1 || lastIndexMigration == -1 {
-		log.Fatalf("the %s %s migration or database version is not in the list of migrations available for updating",
-			beforeThisMigration.VersionDb, beforeThisMigration.Name)
+		return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("the %s %s migration or database version is not in the list of migrations available for updating",
+			beforeThisMigration.VersionDb, beforeThisMigration.Name))
 	}
 
 	migrationGroupsUpTo := make([]domain.MigrationGroup, lastIndexMigrationGroup+1)
@@ -105,7 +136,7 @@ func getMigrationGroupsAndMigrationsBeforeMigration(sortedMigrationGroups []doma
 	if isLastMigrationInLastMigrationGroup {
 		coppiedLastMigrationGroup := lastMigrationGroup.Copy()
 		migrationGroupsUpTo[lastIndexMigrationGroup] = coppiedLastMigrationGroup
-		return migrationGroupsUpTo
+		return migrationGroupsUpTo, nil
 	}
 
 	numberMigrationsInLastMigrationGroup := lastIndexMigration + 1
@@ -117,5 +148,116 @@ func getMigrationGroupsAndMigrationsBeforeMigration(sortedMigrationGroups []doma
 	newMigrationGroup := domain.NewMigrationGroup(lastMigrationGroup.VersionDb, migrationsInLastVersionDbUpTo)
 	migrationGroupsUpTo[lastIndexMigrationGroup] = *newMigrationGroup
 
-	return migrationGroupsUpTo
+	return migrationGroupsUpTo, nil
+}
+
+// A part of migrations that is applied in one way: in a transaction or without it
+type partOfMigrations struct {
+	migrationGroups []domain.MigrationGroup
+
+	// If true, the part consists of one migration that cannot be executed inside a transaction
+	isNoTransaction bool
+}
+
+// Splits migrations into parts so that each migration that cannot be executed inside a transaction is in a separate part.
+// The order of migrations is kept. isNoTransaction selects the script that is checked: the migration or its down script.
+func splitByTransactionMode(migrationGroups []domain.MigrationGroup, isNoTransaction func(*domain.Migration) bool) []partOfMigrations {
+	parts := make([]partOfMigrations, 0)
+	current := make([]domain.MigrationGroup, 0)
+
+	for _, mg := range migrationGroups {
+		migrations := make([]domain.Migration, 0, len(mg.Migrations))
+		for _, migration := range mg.Migrations {
+			if !isNoTransaction(&migration) {
+				migrations = append(migrations, migration)
+				continue
+			}
+
+			if len(migrations) != 0 {
+				current = append(current, *domain.NewMigrationGroup(mg.VersionDb, migrations))
+				migrations = make([]domain.Migration, 0, len(mg.Migrations))
+			}
+			if len(current) != 0 {
+				parts = append(parts, partOfMigrations{migrationGroups: current})
+				current = make([]domain.MigrationGroup, 0)
+			}
+			noTransactionGroup := domain.NewMigrationGroup(mg.VersionDb, []domain.Migration{migration})
+			parts = append(parts, partOfMigrations{migrationGroups: []domain.MigrationGroup{*noTransactionGroup}, isNoTransaction: true})
+		}
+		if len(migrations) != 0 {
+			current = append(current, *domain.NewMigrationGroup(mg.VersionDb, migrations))
+		}
+	}
+	if len(current) != 0 {
+		parts = append(parts, partOfMigrations{migrationGroups: current})
+	}
+	return parts
+}
+
+func isMigrationNoTransaction(migration *domain.Migration) bool {
+	return migration.IsNoTransaction
+}
+
+// The down scripts of the skipped migrations are not executed
+func isDownNoTransaction(migration *domain.Migration) bool {
+	return migration.IsDownNoTransaction && !migration.IsSkipped
+}
+
+// With the dump strategy a dump is created before applying, with the transaction strategies only before
+// the migrations that cannot be executed inside a transaction
+func isDumpNeeded(rollbackStrategy string, migrationGroups []domain.MigrationGroup) bool {
+	if rollbackStrategy == config.RollbackDump {
+		return true
+	}
+	for _, mg := range migrationGroups {
+		for _, migration := range mg.Migrations {
+			if migration.IsNoTransaction {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The versions are inclusive. A date without the time limits the whole day in the local time zone.
+func newHistoryFilter(cfg *config.Config) (*domain.HistoryFilter, error) {
+	filter := &domain.HistoryFilter{}
+	var err error
+	if cfg.HistoryFrom != "" {
+		if filter.FromVersion, err = domain.NewVersionDb(cfg.HistoryFrom); err != nil {
+			return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("wrong version in -from: %w", err))
+		}
+	}
+	if cfg.HistoryTo != "" {
+		if filter.ToVersion, err = domain.NewVersionDb(cfg.HistoryTo); err != nil {
+			return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("wrong version in -to: %w", err))
+		}
+	}
+	if cfg.HistorySince != "" {
+		if filter.Since, _, err = parseHistoryTime(cfg.HistorySince); err != nil {
+			return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("wrong time in -since: %w", err))
+		}
+	}
+	if cfg.HistoryUntil != "" {
+		isDate := false
+		if filter.Until, isDate, err = parseHistoryTime(cfg.HistoryUntil); err != nil {
+			return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("wrong time in -until: %w", err))
+		}
+		if isDate {
+			filter.Until = filter.Until.AddDate(0, 0, 1)
+		}
+	}
+	return filter, nil
+}
+
+// Example: 2023-05-01 or 2023-05-01T15:04:05+03:00. Returns true if only the date is specified.
+func parseHistoryTime(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expected a date (2006-01-02) or a time in RFC 3339 (2006-01-02T15:04:05Z07:00): %s", value)
+	}
+	return t, false, nil
 }
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// AppliedMigration is a migration that has been applied to the database.
+type AppliedMigration struct {
+	Migration *Migration
+
+	// Checksum of the migration file content at the time of applying
+	Checksum  string
+	AppliedAt time.Time
+	Duration  time.Duration
+}
+
+func NewAppliedMigration(migration *Migration, sql string, appliedAt time.Time, duration time.Duration) *AppliedMigration {
+	return &AppliedMigration{
+		Migration: migration,
+		Checksum:  CalculateChecksum(sql),
+		AppliedAt: appliedAt,
+		Duration:  duration,
+	}
+}
+
+// Returns the sha256 of the migration file content in hex
+func CalculateChecksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,27 @@
+package domain
+
+// The states of the database relative to the expected migration
+const (
+	CheckStateMatch = "match"
+
+	// Applying new migrations brings the database to the expected state
+	CheckStateBehind = "behind"
+
+	// The current migration is newer than expected or is not in the directory with migrations
+	CheckStateAheadOrUnknown = "ahead_or_unknown"
+)
+
+// CheckResult is the result of comparing the current migration with the last migration in the directory
+// or with the required version
+type CheckResult struct {
+	State string
+
+	// Why the state is not CheckStateMatch
+	Reason string
+
+	// Nil in initialization mode
+	CurrentMigration *Migration
+
+	// The last migration in the directory with migrations. Nil if there are no migrations.
+	LatestMigration *Migration
+}
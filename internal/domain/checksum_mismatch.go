@@ -0,0 +1,24 @@
+package domain
+
+// ChecksumMismatch is an applied migration whose file has been changed or deleted after applying.
+type ChecksumMismatch struct {
+	Migration *Migration
+
+	// Checksum recorded when applying
+	RecordedChecksum string
+
+	// Checksum of the migration file on disk. Empty if the file does not exist.
+	ActualChecksum string
+}
+
+func NewChecksumMismatch(migration *Migration, recordedChecksum string, actualChecksum string) *ChecksumMismatch {
+	return &ChecksumMismatch{
+		Migration:        migration,
+		RecordedChecksum: recordedChecksum,
+		ActualChecksum:   actualChecksum,
+	}
+}
+
+func (m *ChecksumMismatch) IsFileMissing() bool {
+	return m.ActualChecksum == ""
+}
@@ -6,4 +6,61 @@ var (
 	ErrRequired = errors.New("required value")
 	ErrNotFound = errors.New("not found")
 	ErrNil      = errors.New("nil data")
+
+	// The version or migration specified as the target cannot be used
+	ErrInvalidTarget = errors.New("invalid target")
+
+	// The changes have not been made, the database has been returned to its previous state
+	ErrApplyFailed = errors.New("apply failed")
+
+	// The failed part of the changes has been returned to its previous state, the parts committed before it remain.
+	// Example: the versions applied with transaction-per-version before the failed one.
+	ErrPartiallyApplied = errors.New("partially applied")
+
+	// The database has not been restored from the dump, it must be restored manually
+	ErrRestoreFailed = errors.New("restore failed")
+
+	// The files of applied migrations have been changed after applying
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// Another dbupdater is changing the database, the lock has not been released in time
+	ErrLocked = errors.New("locked")
+
+	// The user has not confirmed the changes of the database
+	ErrNotConfirmed = errors.New("not confirmed")
+
+	// The current migration of the database or the migration files differ from the saved plan
+	ErrPlanMismatch = errors.New("plan mismatch")
+
+	// The connection to the database has not been established
+	ErrConnectionFailed = errors.New("connection failed")
+
+	// The parameters are missing or cannot be used together
+	ErrInvalidParameters = errors.New("invalid parameters")
 )
+
+// kindError adds a kind to the error without changing its text. The kind is checked with errors.Is.
+type kindError struct {
+	kind error
+	err  error
+}
+
+// Example: errors.Is(WithKind(ErrInvalidTarget, err), ErrInvalidTarget) == true
+func WithKind(kind error, err error) error {
+	return &kindError{
+		kind: kind,
+		err:  err,
+	}
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
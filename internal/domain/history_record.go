@@ -0,0 +1,69 @@
+package domain
+
+import "time"
+
+// Outcomes of the records of the history. The manual changes have the outcomes equal to their kinds:
+// ManualChangeMarkApplied, ManualChangeSkip, ManualChangeRepair.
+const (
+	HistoryOutcomeApplied    = "applied"
+	HistoryOutcomeRolledBack = "rolled_back"
+
+	// The current migration has been imported from the legacy mode
+	HistoryOutcomeImported = "imported"
+
+	// The current migration of a database that existed before dbupdater
+	HistoryOutcomeBaseline = "baseline"
+)
+
+// HistoryRecord is one change of the current migration recorded in the history
+type HistoryRecord struct {
+	// The applied migration. For HistoryOutcomeRolledBack it is the reverted migration.
+	Migration *Migration
+
+	// The migration that became current. It differs from Migration only for HistoryOutcomeRolledBack.
+	CurrentMigration *Migration
+
+	// One of the HistoryOutcome* constants or the kind of the manual change
+	Outcome string
+
+	At       time.Time
+	Duration time.Duration
+
+	// The database user
+	DbUser string
+
+	// Empty if the connection was made through a Unix-domain socket or the address was not recorded
+	ClientHost string
+
+	ToolVersion string
+
+	// Empty if the checksum was not recorded
+	Checksum string
+
+	// Only for the manual changes
+	Reason    string
+	ChangedBy string
+}
+
+// HistoryFilter limits the records of the history. The empty fields do not limit.
+type HistoryFilter struct {
+	// The versions of the migrations, inclusive
+	FromVersion *VersionDb
+	ToVersion   *VersionDb
+
+	// The time of the records, Since inclusive, Until exclusive. The repository selects the records by the time.
+	Since time.Time
+	Until time.Time
+}
+
+// Checks the versions of the record, the time is checked when the records are selected
+func (f *HistoryFilter) IsSatisfiedBy(record *HistoryRecord) bool {
+	version := record.Migration.VersionDb
+	if f.FromVersion != nil && version.LessThan(f.FromVersion) {
+		return false
+	}
+	if f.ToVersion != nil && version.GreaterThan(f.ToVersion) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// Lock is the PostgreSQL advisory lock that prevents several dbupdater from changing one database at the same time.
+// The key is derived from the database and the table in which the current migration is tracked.
+type Lock struct {
+	name string
+	key  int64
+}
+
+func NewLock(dbName string, trackingTable string) *Lock {
+	name := fmt.Sprintf("dbupdater:%s:%s", dbName, trackingTable)
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return &Lock{
+		name: name,
+		key:  int64(hash.Sum64()),
+	}
+}
+
+// Example: dbupdater:db_local:dbupdater_history
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// The key for pg_advisory_lock
+func (l *Lock) Key() int64 {
+	return l.key
+}
+
+// LockHolder is the session that holds the lock
+type LockHolder struct {
+	Pid             int
+	ApplicationName string
+
+	// Empty for connections through a unix socket
+	ClientAddr string
+
+	BackendStart time.Time
+}
+
+// Example: pid 1234, application_name dbupdater, client 10.0.0.5, connected at 2023-04-01 10:00:00
+func (h *LockHolder) String() string {
+	applicationName := h.ApplicationName
+	if applicationName == "" {
+		applicationName = "not set"
+	}
+	description := fmt.Sprintf("pid %d, application_name %s", h.Pid, applicationName)
+	if h.ClientAddr != "" {
+		description += ", client " + h.ClientAddr
+	}
+	return description + ", connected at " + h.BackendStart.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// Kinds of changes of the current migration made by a person, nothing is executed
+const (
+	// The migration has been applied by hand, the file is checked with the checksum like after applying
+	ManualChangeMarkApplied = "mark_applied"
+
+	// The migration will never be applied, up continues after it
+	ManualChangeSkip = "skip"
+
+	// The current migration is set after the database has been fixed by hand, for example after a failed restore
+	ManualChangeRepair = "repair"
+)
+
+// ManualChange is a change of the current migration made by a person without executing migrations
+type ManualChange struct {
+	// One of ManualChangeMarkApplied, ManualChangeSkip, ManualChangeRepair
+	Kind string
+
+	// The migration becomes the current one
+	Migration *Migration
+
+	// Checksum of the migration file content, only for ManualChangeMarkApplied
+	Checksum string
+
+	// Why the change has been made
+	Reason string
+
+	// The user of the operating system who has made the change. The database user is recorded by the database.
+	ChangedBy string
+	ChangedAt time.Time
+}
+
+func NewManualChange(kind string, migration *Migration, reason string, changedBy string) *ManualChange {
+	return &ManualChange{
+		Kind:      kind,
+		Migration: migration,
+		Reason:    reason,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+	}
+}
@@ -12,6 +12,18 @@ type Migration struct {
 
 	// name - is the number + name of the migration. Example: 0001.InitMigration1
 	Name string
+
+	// The migration cannot be executed inside a transaction. Example: CREATE INDEX CONCURRENTLY
+	IsNoTransaction bool
+
+	// There is a script that reverts the migration
+	HasDown bool
+
+	// The script that reverts the migration cannot be executed inside a transaction. Example: DROP INDEX CONCURRENTLY
+	IsDownNoTransaction bool
+
+	// The migration has been recorded with skip and has not been applied after it, rollback has nothing to revert
+	IsSkipped bool
 }
 
 func NewMigration(name string, versionDb *VersionDb) (*Migration, error) {
@@ -29,6 +41,14 @@ func (first *Migration) IsEqual(second *Migration) bool {
 	return first.VersionDb.Equal(second.VersionDb) && first.Name == second.Name
 }
 
+// Migrations are ordered by the database version, inside the version by the number in the name
+func (first *Migration) IsAfter(second *Migration) bool {
+	if !first.VersionDb.Equal(second.VersionDb) {
+		return first.VersionDb.GreaterThan(second.VersionDb)
+	}
+	return first.Name > second.Name
+}
+
 // Migration file name example: 0001.Example
 func isCorrectMigrationName(name string) bool {
 	partsOfTheName := strings.Split(name, ".")
@@ -0,0 +1,30 @@
+package domain
+
+// MigrationError is the error of the migration that has not been applied. The text of the error is not changed.
+// The migration is received with errors.As.
+type MigrationError struct {
+	Migration *Migration
+
+	// The place of the error in the migration file, nil if the database has not reported it
+	Position *SqlPosition
+
+	// The statement that has failed if the statements of the migration are executed one by one
+	Statement *FailedStatement
+
+	err error
+}
+
+func NewMigrationError(migration *Migration, err error) *MigrationError {
+	return &MigrationError{
+		Migration: migration,
+		err:       err,
+	}
+}
+
+func (e *MigrationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.err
+}
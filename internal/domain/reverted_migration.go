@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// RevertedMigration is a migration whose down script has been applied to the database.
+type RevertedMigration struct {
+	Migration *Migration
+
+	// The migration that became current after the revert
+	CurrentMigration *Migration
+
+	RevertedAt time.Time
+	Duration   time.Duration
+}
+
+func NewRevertedMigration(migration *Migration, currentMigration *Migration, revertedAt time.Time, duration time.Duration) *RevertedMigration {
+	return &RevertedMigration{
+		Migration:        migration,
+		CurrentMigration: currentMigration,
+		RevertedAt:       revertedAt,
+		Duration:         duration,
+	}
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// SavedPlan is the list of migrations determined by plan and saved to apply exactly it later
+type SavedPlan struct {
+	CreatedAt time.Time
+
+	// The current migration of the database when the plan was made. In initialization mode it is the initial migration.
+	StartMigration *Migration
+	IsInitMode     bool
+
+	// The migrations in the order of applying
+	Migrations []PlannedMigration
+}
+
+// PlannedMigration is a migration of the plan with the checksum of its file at the time of planning
+type PlannedMigration struct {
+	Migration *Migration
+	Checksum  string
+}
+
+func NewSavedPlan(startMigration *Migration, isInitMode bool, migrations []PlannedMigration) *SavedPlan {
+	return &SavedPlan{
+		CreatedAt:      time.Now(),
+		StartMigration: startMigration,
+		IsInitMode:     isInitMode,
+		Migrations:     migrations,
+	}
+}
+
+// Returns the last migration of the plan, nil if there are no migrations in the plan
+func (p *SavedPlan) LastMigration() *Migration {
+	if len(p.Migrations) == 0 {
+		return nil
+	}
+	return p.Migrations[len(p.Migrations)-1].Migration
+}
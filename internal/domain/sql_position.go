@@ -0,0 +1,77 @@
+package domain
+
+import "strings"
+
+// The number of lines shown before and after the line of the error
+const contextLinesOfSqlPosition = 2
+
+// SqlPosition is the place in the migration file where PostgreSQL has found the error
+type SqlPosition struct {
+	// From 1
+	Line   int
+	Column int
+
+	// The line of the error with the lines around it
+	Lines []SqlLine
+}
+
+type SqlLine struct {
+	// From 1
+	Number int
+	Text   string
+}
+
+// The position is the number of the character in the sql text from 1, as PostgreSQL reports it.
+// Returns nil if the position is not in the text.
+func NewSqlPosition(sql string, position int) *SqlPosition {
+	runes := []rune(sql)
+	if position < 1 || position > len(runes)+1 {
+		return nil
+	}
+
+	before := string(runes[:position-1])
+	line := strings.Count(before, "\n") + 1
+	column := len([]rune(before[strings.LastIndex(before, "\n")+1:])) + 1
+
+	allLines := strings.Split(sql, "\n")
+	first := line - contextLinesOfSqlPosition
+	if first < 1 {
+		first = 1
+	}
+	last := line + contextLinesOfSqlPosition
+	if last > len(allLines) {
+		last = len(allLines)
+	}
+	lines := make([]SqlLine, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		lines = append(lines, SqlLine{Number: number, Text: strings.TrimRight(allLines[number-1], "\r")})
+	}
+
+	return &SqlPosition{
+		Line:   line,
+		Column: column,
+		Lines:  lines,
+	}
+}
+
+// Returns the text of the line of the error before the column, the tabs are kept to place a marker under the column
+func (p *SqlPosition) IndentOfColumn() string {
+	for _, l := range p.Lines {
+		if l.Number != p.Line {
+			continue
+		}
+		var indent strings.Builder
+		for i, r := range []rune(l.Text) {
+			if i >= p.Column-1 {
+				break
+			}
+			if r == '\t' {
+				indent.WriteRune('\t')
+				continue
+			}
+			indent.WriteRune(' ')
+		}
+		return indent.String()
+	}
+	return ""
+}
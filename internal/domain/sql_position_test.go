@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSqlPosition(t *testing.T) {
+	testCases := []struct {
+		name             string
+		sql              string
+		position         int
+		expectedPosition *SqlPosition
+	}{
+		{
+			name:     "FirstLine",
+			sql:      "select x;",
+			position: 8,
+			expectedPosition: &SqlPosition{Line: 1, Column: 8, Lines: []SqlLine{
+				{Number: 1, Text: "select x;"},
+			}},
+		},
+		{
+			name:     "LinesAround",
+			sql:      "select 1;\nselect 2;\nselect x;\nselect 4;\nselect 5;\nselect 6;",
+			position: 28,
+			expectedPosition: &SqlPosition{Line: 3, Column: 8, Lines: []SqlLine{
+				{Number: 1, Text: "select 1;"},
+				{Number: 2, Text: "select 2;"},
+				{Number: 3, Text: "select x;"},
+				{Number: 4, Text: "select 4;"},
+				{Number: 5, Text: "select 5;"},
+			}},
+		},
+		{
+			name:     "MultiByteCharactersBefore",
+			sql:      "select 'привет';\nselect 'мир', x;",
+			position: 32,
+			expectedPosition: &SqlPosition{Line: 2, Column: 15, Lines: []SqlLine{
+				{Number: 1, Text: "select 'привет';"},
+				{Number: 2, Text: "select 'мир', x;"},
+			}},
+		},
+		{
+			name:     "Crlf",
+			sql:      "select 1;\r\nselect x;\r\n",
+			position: 19,
+			expectedPosition: &SqlPosition{Line: 2, Column: 8, Lines: []SqlLine{
+				{Number: 1, Text: "select 1;"},
+				{Number: 2, Text: "select x;"},
+				{Number: 3, Text: ""},
+			}},
+		},
+		{
+			name:     "EndOfText",
+			sql:      "select",
+			position: 7,
+			expectedPosition: &SqlPosition{Line: 1, Column: 7, Lines: []SqlLine{
+				{Number: 1, Text: "select"},
+			}},
+		},
+		{name: "Zero", sql: "select x;", position: 0, expectedPosition: nil},
+		{name: "AfterEndOfText", sql: "select x;", position: 11, expectedPosition: nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			position := NewSqlPosition(tc.sql, tc.position)
+			if !reflect.DeepEqual(position, tc.expectedPosition) {
+				t.Errorf("Expected position %+v, received %+v", tc.expectedPosition, position)
+			}
+		})
+	}
+}
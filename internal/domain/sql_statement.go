@@ -0,0 +1,212 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// SqlStatement is one statement of the migration file executed separately with -statements
+type SqlStatement struct {
+	// The text from the first character of the statement to the semicolon inclusive, without the comments before it
+	Text string
+
+	// The number of the first character of Text in the migration file from 0, in characters as PostgreSQL counts them
+	Offset int
+
+	// The line of the first character of Text in the migration file from 1
+	Line int
+
+	// The statement is COPY ... FROM stdin, the rows follow it in the file up to the line \.
+	IsCopyFromStdin bool
+	CopyData        string
+}
+
+// COPY table [(columns)] FROM stdin [WITH (options)]
+var copyFromStdinRegexp = regexp.MustCompile(`(?is)^copy\s.*\sfrom\s+stdin\b`)
+
+// Splits the sql text into statements at the semicolons that are not in quotes, dollar quotes or comments.
+// Comments and whitespace between the statements are not statements. The text after the last semicolon is the last statement.
+func SplitSqlStatements(sql string) []SqlStatement {
+	text := []rune(sql)
+	statements := make([]SqlStatement, 0)
+	start := -1
+	for i := 0; i < len(text); {
+		if start == -1 {
+			if unicode.IsSpace(text[i]) {
+				i++
+				continue
+			}
+			if end, ok := skipComment(text, i); ok {
+				i = end
+				continue
+			}
+			start = i
+		}
+
+		switch end, isSkipped := skipQuotedOrComment(text, i); {
+		case isSkipped:
+			i = end
+		case text[i] == ';':
+			i++
+			statement := SqlStatement{Text: string(text[start:i]), Offset: start}
+			if copyFromStdinRegexp.MatchString(statement.Text) {
+				statement.IsCopyFromStdin = true
+				statement.CopyData, i = readCopyData(text, i)
+			}
+			statements = append(statements, statement)
+			start = -1
+		default:
+			i++
+		}
+	}
+	if start != -1 {
+		statements = append(statements, SqlStatement{Text: string(text[start:]), Offset: start})
+	}
+
+	line, lineOffset := 1, 0
+	for i := range statements {
+		line += strings.Count(string(text[lineOffset:statements[i].Offset]), "\n")
+		lineOffset = statements[i].Offset
+		statements[i].Line = line
+	}
+	return statements
+}
+
+// FailedStatement is the statement of the migration that PostgreSQL has rejected with -statements
+type FailedStatement struct {
+	// From 1
+	Number int
+	Count  int
+
+	Statement SqlStatement
+}
+
+// Returns the position after the comment if a comment starts at i
+func skipComment(text []rune, i int) (int, bool) {
+	switch {
+	case hasPrefixAt(text, i, "--"):
+		for i < len(text) && text[i] != '\n' {
+			i++
+		}
+		return i, true
+	case hasPrefixAt(text, i, "/*"):
+		// Block comments can be nested
+		depth := 0
+		for i < len(text) {
+			switch {
+			case hasPrefixAt(text, i, "/*"):
+				depth++
+				i += 2
+			case hasPrefixAt(text, i, "*/"):
+				depth--
+				i += 2
+				if depth == 0 {
+					return i, true
+				}
+			default:
+				i++
+			}
+		}
+		return i, true
+	}
+	return i, false
+}
+
+// Returns the position after the string, the quoted identifier, the dollar-quoted string or the comment if it starts at i.
+// Unterminated ones last to the end of the text, PostgreSQL reports the error.
+func skipQuotedOrComment(text []rune, i int) (int, bool) {
+	if end, ok := skipComment(text, i); ok {
+		return end, true
+	}
+
+	switch text[i] {
+	case '\'':
+		// E'...' allows escaping the quote with a backslash
+		isEscapeString := i > 0 && (text[i-1] == 'E' || text[i-1] == 'e') && (i == 1 || !isIdentifierRune(text[i-2]))
+		return skipQuoted(text, i, '\'', isEscapeString), true
+	case '"':
+		return skipQuoted(text, i, '"', false), true
+	case '$':
+		if i > 0 && isIdentifierRune(text[i-1]) {
+			return i, false
+		}
+		tag, ok := dollarQuoteTag(text, i)
+		if !ok {
+			return i, false
+		}
+		for j := i + len(tag); j < len(text); j++ {
+			if hasPrefixAt(text, j, tag) {
+				return j + len(tag), true
+			}
+		}
+		return len(text), true
+	}
+	return i, false
+}
+
+// The quote inside is doubled: "a ""b"""
+func skipQuoted(text []rune, i int, quote rune, isEscapeString bool) int {
+	for i++; i < len(text); i++ {
+		switch {
+		case isEscapeString && text[i] == '\\':
+			i++
+		case text[i] == quote && i+1 < len(text) && text[i+1] == quote:
+			i++
+		case text[i] == quote:
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+// Returns the tag with the dollars if a dollar quote starts at i. Example: $$, $body$
+func dollarQuoteTag(text []rune, i int) (string, bool) {
+	for j := i + 1; j < len(text); j++ {
+		if text[j] == '$' {
+			return string(text[i : j+1]), true
+		}
+		// The tag cannot start with a digit, $1 is a parameter
+		if !isIdentifierRune(text[j]) || (j == i+1 && unicode.IsDigit(text[j])) {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// The rows of COPY FROM stdin start on the line after the statement and end with the line \.
+// Returns the rows and the position after the line \.
+func readCopyData(text []rune, i int) (string, int) {
+	for i < len(text) && text[i] != '\n' {
+		i++
+	}
+	if i < len(text) {
+		i++
+	}
+	start := i
+	for i < len(text) {
+		lineEnd := i
+		for lineEnd < len(text) && text[lineEnd] != '\n' {
+			lineEnd++
+		}
+		if strings.TrimRight(string(text[i:lineEnd]), "\r") == `\.` {
+			return string(text[start:i]), lineEnd
+		}
+		i = lineEnd + 1
+	}
+	return string(text[start:]), len(text)
+}
+
+func hasPrefixAt(text []rune, i int, prefix string) bool {
+	for _, r := range prefix {
+		if i >= len(text) || text[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
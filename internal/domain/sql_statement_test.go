@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSqlStatements(t *testing.T) {
+	testCases := []struct {
+		name               string
+		sql                string
+		expectedStatements []SqlStatement
+	}{
+		{
+			name: "Semicolons",
+			sql:  "create table t ( id int );\nselect 1;",
+			expectedStatements: []SqlStatement{
+				{Text: "create table t ( id int );", Offset: 0, Line: 1},
+				{Text: "select 1;", Offset: 27, Line: 2},
+			},
+		},
+		{
+			name: "QuotesDoubled",
+			sql:  `select 'a;''b', "c;""d";select 2;`,
+			expectedStatements: []SqlStatement{
+				{Text: `select 'a;''b', "c;""d";`, Offset: 0, Line: 1},
+				{Text: "select 2;", Offset: 24, Line: 1},
+			},
+		},
+		{
+			name: "EscapeString",
+			sql:  `select E'it\'s; fine', e'\\';select 2;`,
+			expectedStatements: []SqlStatement{
+				{Text: `select E'it\'s; fine', e'\\';`, Offset: 0, Line: 1},
+				{Text: "select 2;", Offset: 29, Line: 1},
+			},
+		},
+		{
+			name: "BackslashInStandardString",
+			sql:  `select 'a\';select 2;`,
+			expectedStatements: []SqlStatement{
+				{Text: `select 'a\';`, Offset: 0, Line: 1},
+				{Text: "select 2;", Offset: 12, Line: 1},
+			},
+		},
+		{
+			name: "NestedBlockComments",
+			sql:  "/* outer /* inner; */ still; */ select /* a; /* b; */ c; */ 1;\nselect 2;",
+			expectedStatements: []SqlStatement{
+				{Text: "select /* a; /* b; */ c; */ 1;", Offset: 32, Line: 1},
+				{Text: "select 2;", Offset: 63, Line: 2},
+			},
+		},
+		{
+			name: "LineComments",
+			sql:  "-- first; comment\nselect 1; -- second;\nselect 2;",
+			expectedStatements: []SqlStatement{
+				{Text: "select 1;", Offset: 18, Line: 2},
+				{Text: "select 2;", Offset: 39, Line: 3},
+			},
+		},
+		{
+			name: "ParameterAndDollarQuote",
+			sql:  "prepare p as select $1;create function f() returns int as $body$ select 1; $$ $body$ language sql;",
+			expectedStatements: []SqlStatement{
+				{Text: "prepare p as select $1;", Offset: 0, Line: 1},
+				{Text: "create function f() returns int as $body$ select 1; $$ $body$ language sql;", Offset: 23, Line: 1},
+			},
+		},
+		{
+			name: "DollarInIdentifier",
+			sql:  "select 1 as a$b; select 2;",
+			expectedStatements: []SqlStatement{
+				{Text: "select 1 as a$b;", Offset: 0, Line: 1},
+				{Text: "select 2;", Offset: 17, Line: 1},
+			},
+		},
+		{
+			name: "CopyFromStdinWithCrlf",
+			sql:  "copy t (id) from stdin;\r\n1;\r\n2\r\n\\.\r\nselect 1;",
+			expectedStatements: []SqlStatement{
+				{Text: "copy t (id) from stdin;", Offset: 0, Line: 1, IsCopyFromStdin: true, CopyData: "1;\r\n2\r\n"},
+				{Text: "select 1;", Offset: 36, Line: 5},
+			},
+		},
+		{
+			name: "TrailingStatementWithoutSemicolon",
+			sql:  "select 1;\n\nselect 2\n",
+			expectedStatements: []SqlStatement{
+				{Text: "select 1;", Offset: 0, Line: 1},
+				{Text: "select 2\n", Offset: 11, Line: 3},
+			},
+		},
+		{
+			name: "MultiByteCharacters",
+			sql:  "select 'привет;';\nselect 'мир';",
+			expectedStatements: []SqlStatement{
+				{Text: "select 'привет;';", Offset: 0, Line: 1},
+				{Text: "select 'мир';", Offset: 18, Line: 2},
+			},
+		},
+		{
+			name:               "OnlyComments",
+			sql:                "-- nothing\n/* to apply */\n",
+			expectedStatements: []SqlStatement{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			statements := SplitSqlStatements(tc.sql)
+			if !reflect.DeepEqual(statements, tc.expectedStatements) {
+				t.Errorf("Expected statements %+v, received %+v", tc.expectedStatements, statements)
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@ package domain
 import (
 	"fmt"
 
-	"dbupdater/helper"
+	"github.com/awsoremod/DBUpdater/helper"
 
 	"github.com/hashicorp/go-version"
 )
@@ -42,3 +42,53 @@ func (first *VersionDb) GreaterThan(second *VersionDb) bool {
 func (first *VersionDb) LessThan(second *VersionDb) bool {
 	return first.version.LessThan(second.version)
 }
+
+// VersionConstraint is the database versions expected by an application. Example: >= v0.0.3, < v0.1
+// A version without an operator means exactly this version.
+type VersionConstraint struct {
+	constraints version.Constraints
+	original    string
+}
+
+func NewVersionConstraint(str string) (*VersionConstraint, error) {
+	constraints, err := version.NewConstraint(str)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionConstraint{
+		constraints: constraints,
+		original:    str,
+	}, nil
+}
+
+func (c *VersionConstraint) String() string {
+	return c.original
+}
+
+func (c *VersionConstraint) IsSatisfiedBy(v *VersionDb) bool {
+	return c.constraints.Check(v.version)
+}
+
+// Example: v0.0.4 -> v0.0.5
+func (v *VersionDb) NextPatch() *VersionDb {
+	segments := v.version.Segments()
+	return newVersionDbFromSegments(segments[0], segments[1], segments[2]+1)
+}
+
+// Example: v0.0.4 -> v0.1.0
+func (v *VersionDb) NextMinor() *VersionDb {
+	segments := v.version.Segments()
+	return newVersionDbFromSegments(segments[0], segments[1]+1, 0)
+}
+
+// Example: v0.0.4 -> v1.0.0
+func (v *VersionDb) NextMajor() *VersionDb {
+	segments := v.version.Segments()
+	return newVersionDbFromSegments(segments[0]+1, 0, 0)
+}
+
+func newVersionDbFromSegments(major int, minor int, patch int) *VersionDb {
+	return &VersionDb{
+		version: version.Must(version.NewVersion(fmt.Sprintf("v%d.%d.%d", major, minor, patch))),
+	}
+}
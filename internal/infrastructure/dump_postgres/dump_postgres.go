@@ -5,38 +5,69 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
 
-	"dbupdater/config"
-	"dbupdater/helper"
-	"dbupdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
 )
 
 type DumpPostgres struct {
-	pathToDumpUtility    string
-	pathToRestoreUtility string
-	dbEntry              config.DbEntry
+	dumpUtility    *utility
+	restoreUtility *utility
+	dbEntry        config.DbEntry
+
+	// The connection strings are passed in --dbname, the password is passed in PGPASSWORD
+	connStringToDump    string
+	connStringToRestore string
+	password            string
 }
 
-func NewDumpPostgres(dbEntry config.DbEntry) (*DumpPostgres, error) {
-	pathToDumpUtility, err := getPathToDumpUtility()
+// Finds pg_dump and pg_restore that can work with the server of the specified version.
+// serverVersion is the server_version parameter reported by the server.
+func NewDumpPostgres(dbEntry config.DbEntry, utilities config.DumpUtilities, serverVersion string) (*DumpPostgres, error) {
+	parsedServerVersion, err := parseServerVersion(serverVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the server version: %w", err)
+	}
+
+	dumpUtility, err := findUtility(nameDumpUtility, utilities.PathToDumpUtility, parsedServerVersion)
+	if err != nil {
+		return nil, err
+	}
+	restoreUtility, err := findUtility(nameRestoreUtility, utilities.PathToRestoreUtility, parsedServerVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	connStringToDump, password, err := helper.ConnStringWithoutPassword(&dbEntry)
 	if err != nil {
 		return nil, err
 	}
-	pathToRestoreUtility, err := getPathToRestoreUtility()
+	// pg_restore connects to the database 'postgres' to recreate the database
+	entryToRestore := dbEntry
+	entryToRestore.DbName = "postgres"
+	connStringToRestore, _, err := helper.ConnStringWithoutPassword(&entryToRestore)
 	if err != nil {
 		return nil, err
 	}
 
 	return &DumpPostgres{
-		dbEntry:              dbEntry,
-		pathToDumpUtility:    pathToDumpUtility,
-		pathToRestoreUtility: pathToRestoreUtility,
+		dbEntry:             dbEntry,
+		dumpUtility:         dumpUtility,
+		restoreUtility:      restoreUtility,
+		connStringToDump:    connStringToDump,
+		connStringToRestore: connStringToRestore,
+		password:            password,
 	}, nil
 }
 
+// Returns which pg_dump and pg_restore were selected
+func (infra *DumpPostgres) GetDescriptionOfUtilities() string {
+	return fmt.Sprintf("%s: %s\n%s: %s", nameDumpUtility, infra.dumpUtility, nameRestoreUtility, infra.restoreUtility)
+}
+
 // Creates a database dump using the pg_dump utility. The dump file
 // is placed in the specified folder. A connection string is also created in the pgpass file.
 // The dump contains information about the database owner. The path to the dump is returned.
@@ -48,8 +79,8 @@ func (uc *DumpPostgres) Create(_ context.Context, pathForSaveDumps string) (*dom
 	}
 	parameters := uc.getParametersForDumpUtility(pathToDump)
 
-	cmd := exec.Command(uc.pathToDumpUtility, parameters...)
-	cmd.Env = append(cmd.Env, "PGPASSWORD="+uc.dbEntry.Password)
+	cmd := exec.Command(uc.dumpUtility.path, parameters...)
+	cmd.Env = uc.getEnvForUtility()
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -64,8 +95,8 @@ func (uc *DumpPostgres) Create(_ context.Context, pathForSaveDumps string) (*dom
 func (infra *DumpPostgres) Restore(_ context.Context, dump *domain.Dump) error {
 	parameters := infra.getParametersForRestoreUtility(dump.Path())
 
-	cmd := exec.Command(infra.pathToRestoreUtility, parameters...)
-	cmd.Env = append(cmd.Env, "PGPASSWORD="+infra.dbEntry.Password)
+	cmd := exec.Command(infra.restoreUtility.path, parameters...)
+	cmd.Env = infra.getEnvForUtility()
 
 	output, err := cmd.CombinedOutput()
 	strOutput := string(output)
@@ -82,14 +113,14 @@ func (infra *DumpPostgres) Restore(_ context.Context, dump *domain.Dump) error {
 }
 
 func (infra *DumpPostgres) GetCommandToRestoreDump(dump *domain.Dump) (string, error) {
-	pathToRestoreUtility, err := getPathToRestoreUtility()
-	if err != nil {
-		return "", err
-	}
-
 	params := infra.getParametersForRestoreUtility(dump.Path())
+	for i, param := range params {
+		if strings.ContainsAny(param, " '") {
+			params[i] = `"` + param + `"`
+		}
+	}
 	strParams := strings.Join(params, " ")
-	commandToRestoreDump := pathToRestoreUtility + ` ` + strParams
+	commandToRestoreDump := infra.restoreUtility.path + ` ` + strParams
 	commandToRestoreDump = strings.Replace(commandToRestoreDump, " --no-password ", " ", 1)
 	return commandToRestoreDump, nil
 }
@@ -109,33 +140,29 @@ func (infra *DumpPostgres) getPathForNewDump(pathForSaveDumps string) string {
 	return pathToDump
 }
 
+// The utilities read PG* environment variables, the password file and the service file in the same way as dbupdater
+func (infra *DumpPostgres) getEnvForUtility() []string {
+	env := os.Environ()
+	if infra.password != "" {
+		env = append(env, "PGPASSWORD="+infra.password)
+	}
+	return env
+}
+
 func (infra *DumpPostgres) getParametersForDumpUtility(pathToDump string) []string {
 	parameters := []string{
-		`--host=` + infra.dbEntry.Host,
-		`--port=` + infra.dbEntry.Port,
-		`--username=` + infra.dbEntry.User,
 		`--no-password`,
 		`--format=` + `custom`,
 		`--create`,
 		`--clean`,
 		`--if-exists`,
-		`--dbname=` + infra.dbEntry.DbName,
+		`--dbname=` + infra.connStringToDump,
 		`--file=` + pathToDump,
 	}
 
 	return parameters
 }
 
-func getPathToDumpUtility() (string, error) {
-	pathToExecutable, err := os.Executable()
-	if err != nil {
-		return "", err
-	}
-	pathToDirWithCurrentlyExecutable := filepath.Dir(pathToExecutable)
-	pathToDumpUtility := fmt.Sprintf("%s/pg_dump_restore_15_2/pg_dump.exe", pathToDirWithCurrentlyExecutable)
-	return pathToDumpUtility, nil
-}
-
 // Forms a parameters to run the pg_restore utility.
 // First the existing database is deleted, then the database from the dump is created.
 // Connects to the database 'postgres'
@@ -146,26 +173,13 @@ func getPathToDumpUtility() (string, error) {
 // 4) There should be no active connections to the database with the same name as in the dump.
 func (infra *DumpPostgres) getParametersForRestoreUtility(pathToDump string) []string {
 	parameters := []string{
-		`--host=` + infra.dbEntry.Host,
-		`--port=` + infra.dbEntry.Port,
-		`--username=` + infra.dbEntry.User,
 		`--no-password`,
 		`--format=` + `custom`,
 		`--create`,
 		`--clean`,
 		`--if-exists`,
-		`--dbname=` + `postgres`,
+		`--dbname=` + infra.connStringToRestore,
 		pathToDump,
 	}
 	return parameters
 }
-
-func getPathToRestoreUtility() (string, error) {
-	pathToExecutable, err := os.Executable()
-	if err != nil {
-		return "", err
-	}
-	pathToDirWithCurrentlyExecutable := filepath.Dir(pathToExecutable)
-	pathToRestoreUtility := fmt.Sprintf("%s/pg_dump_restore_15_2/pg_restore.exe", pathToDirWithCurrentlyExecutable)
-	return pathToRestoreUtility, nil
-}
@@ -0,0 +1,190 @@
+package dump_postgres
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	nameDumpUtility    = "pg_dump"
+	nameRestoreUtility = "pg_restore"
+
+	// The directory next to the executable file that was used to deliver the utilities on Windows
+	nameDirWithBundledUtilities = "pg_dump_restore_15_2"
+
+	sourceExplicitPath = "the explicitly specified path"
+)
+
+// Directories where PostgreSQL is usually installed. Asterisk is the major version of PostgreSQL.
+var wellKnownDirsWithUtilities = []string{
+	"/usr/lib/postgresql/*/bin",
+	"/usr/pgsql-*/bin",
+	"/usr/local/pgsql/bin",
+	"/opt/homebrew/opt/postgresql@*/bin",
+	"/usr/local/opt/postgresql@*/bin",
+	`C:\Program Files\PostgreSQL\*\bin`,
+}
+
+// utility is a found pg_dump or pg_restore binary
+type utility struct {
+	path    string
+	version *version.Version
+
+	// Where the utility was found. Example: $PATH
+	source string
+}
+
+func (u *utility) String() string {
+	return fmt.Sprintf("%s (version %s, found in %s)", u.path, u.version.Original(), u.source)
+}
+
+// Looks for the utility with the specified name.
+// If explicitPath is specified, only it is checked. Otherwise, the utility is searched next to the executable file,
+// in $PATH and in the well-known PostgreSQL installation directories.
+// The utility whose major version is the closest to the server version, but not lower, is selected.
+func findUtility(name string, explicitPath string, serverVersion *version.Version) (*utility, error) {
+	if explicitPath != "" {
+		u, err := newUtility(explicitPath, sourceExplicitPath)
+		if err != nil {
+			return nil, fmt.Errorf("explicitly specified %s: %w", name, err)
+		}
+		if !isCompatible(u.version, serverVersion) {
+			return nil, fmt.Errorf("explicitly specified %s has version %s, which cannot work with server version %s",
+				name, u.version.Original(), serverVersion.Original())
+		}
+		return u, nil
+	}
+
+	found := make([]*utility, 0)
+	for _, candidate := range getCandidates(name) {
+		u, err := newUtility(candidate.path, candidate.source)
+		if err != nil {
+			continue
+		}
+		found = append(found, u)
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%s was not found in $PATH and in the well-known directories, "+
+			"specify the path to it explicitly", name)
+	}
+
+	compatible := make([]*utility, 0, len(found))
+	for _, u := range found {
+		if isCompatible(u.version, serverVersion) {
+			compatible = append(compatible, u)
+		}
+	}
+	if len(compatible) == 0 {
+		descriptions := make([]string, 0, len(found))
+		for _, u := range found {
+			descriptions = append(descriptions, u.String())
+		}
+		return nil, fmt.Errorf("no %s was found that can work with server version %s, found: %s",
+			name, serverVersion.Original(), strings.Join(descriptions, "; "))
+	}
+
+	// The closest major version is preferred, within one major version the newest one
+	sort.SliceStable(compatible, func(i, j int) bool {
+		first, second := majorVersion(compatible[i].version), majorVersion(compatible[j].version)
+		if first != second {
+			return first < second
+		}
+		return compatible[i].version.GreaterThan(compatible[j].version)
+	})
+	return compatible[0], nil
+}
+
+type candidate struct {
+	path   string
+	source string
+}
+
+// Returns the paths where the utility can be, in order of priority. The paths may not exist.
+func getCandidates(name string) []candidate {
+	fileName := name
+	if runtime.GOOS == "windows" {
+		fileName += ".exe"
+	}
+
+	candidates := make([]candidate, 0)
+	if pathToExecutable, err := os.Executable(); err == nil {
+		pathToBundled := filepath.Join(filepath.Dir(pathToExecutable), nameDirWithBundledUtilities, fileName)
+		candidates = append(candidates, candidate{path: pathToBundled, source: nameDirWithBundledUtilities})
+	}
+
+	if pathInPath, err := exec.LookPath(fileName); err == nil {
+		candidates = append(candidates, candidate{path: pathInPath, source: "$PATH"})
+	}
+
+	for _, pattern := range wellKnownDirsWithUtilities {
+		dirs, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, dir := range dirs {
+			candidates = append(candidates, candidate{path: filepath.Join(dir, fileName), source: dir})
+		}
+	}
+	return candidates
+}
+
+// Checks the existence of the utility and gets its version by running it with --version
+func newUtility(path string, source string) (*utility, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error when getting the version of %s: %w", path, err)
+	}
+	// Example: pg_dump (PostgreSQL) 15.2 (Ubuntu 15.2-1.pgdg22.04+1)
+	fields := strings.Fields(string(output))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("unexpected output of %s --version: %s", path, output)
+	}
+	utilityVersion, err := version.NewVersion(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected output of %s --version: %w", path, err)
+	}
+
+	return &utility{
+		path:    path,
+		version: utilityVersion,
+		source:  source,
+	}, nil
+}
+
+// Parses the server_version parameter reported by the server. Example: 15.2 (Debian 15.2-1.pgdg110+1)
+func parseServerVersion(serverVersion string) (*version.Version, error) {
+	fields := strings.Fields(serverVersion)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("the server did not report its version")
+	}
+	return version.NewVersion(fields[0])
+}
+
+// pg_dump and pg_restore can work with servers of the same or an older major version
+func isCompatible(utilityVersion *version.Version, serverVersion *version.Version) bool {
+	return majorVersion(utilityVersion) >= majorVersion(serverVersion)
+}
+
+// Before PostgreSQL 10 the major version consisted of two numbers. Example: 9.6 -> 906, 15.2 -> 1500
+func majorVersion(v *version.Version) int {
+	segments := v.Segments()
+	if segments[0] >= 10 {
+		return segments[0] * 100
+	}
+	return segments[0]*100 + segments[1]
+}
@@ -0,0 +1,79 @@
+package migration_disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+// Creates migration files and database version directories in the directory with migrations
+type MigrationDirRepo struct {
+	pathToMigrations string
+}
+
+func NewMigrationDirRepo(pathToMigrations string) *MigrationDirRepo {
+	return &MigrationDirRepo{
+		pathToMigrations: pathToMigrations,
+	}
+}
+
+// Creates the migration file with the header, the directives of the migration are written before it.
+// Returns the path of the created file. The existing file is not overwritten.
+func (r *MigrationDirRepo) CreateMigrationFile(_ context.Context, migration *domain.Migration, header string) (string, error) {
+	content := header
+	if migration.IsNoTransaction {
+		content = fmt.Sprintf("-- %s\n%s", directiveNoTransaction, content)
+	}
+	path := filepath.Join(r.pathToMigrations, migration.VersionDb.String(),
+		fmt.Sprintf("%s.%s", migration.Name, extensionForMigrationFiles))
+	return path, createFile(path, content)
+}
+
+// Creates the file of the script that reverts the migration. Example: 0003.CreateTest1.down.sql
+func (r *MigrationDirRepo) CreateDownMigrationFile(_ context.Context, migration *domain.Migration, header string) (string, error) {
+	content := header
+	if migration.IsDownNoTransaction {
+		content = fmt.Sprintf("-- %s\n%s", directiveNoTransaction, content)
+	}
+	path := filepath.Join(r.pathToMigrations, migration.VersionDb.String(),
+		fmt.Sprintf("%s%s.%s", migration.Name, downSuffix, extensionForMigrationFiles))
+	return path, createFile(path, content)
+}
+
+// Returns the path of the created directory. The existing directory is not reused.
+func (r *MigrationDirRepo) CreateVersionDir(_ context.Context, version *domain.VersionDb) (string, error) {
+	path := filepath.Join(r.pathToMigrations, version.String())
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return "", fmt.Errorf("error when creating the directory %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Reads the template of the header of new migrations. Returns domain.ErrNotFound if there is no file.
+func (r *MigrationDirRepo) ReadTemplate(_ context.Context, path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%s: %w", path, domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func createFile(path string, content string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error when creating the file %s: %w", path, err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return fmt.Errorf("error when writing the file %s: %w", path, err)
+	}
+	return file.Close()
+}
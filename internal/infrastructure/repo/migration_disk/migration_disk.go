@@ -2,55 +2,90 @@ package migration_disk
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
-	"dbupdater/helper"
-	"dbupdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
 )
 
+// Reads migrations from a directory, an archive or files embedded with go:embed
 type MigrationDiskRepo struct {
-	IsVerbose        bool
-	PathToMigrations string
+	printer      *helper.Printer
+	MigrationsFS fs.FS
 }
 
-func NewMigrationDiskRepoo(pathToMigrations string, isVerbose bool) *MigrationDiskRepo {
+func NewMigrationDiskRepoo(migrationsFS fs.FS, printer *helper.Printer) *MigrationDiskRepo {
 	return &MigrationDiskRepo{
-		IsVerbose:        isVerbose,
-		PathToMigrations: pathToMigrations,
+		printer:      printer,
+		MigrationsFS: migrationsFS,
 	}
 }
 
 // Returns migrations in the specified database version in ascending order starting from 0001
-func (r *MigrationDiskRepo) GetSortedMigrations(_ context.Context, version *domain.VersionDb) (*domain.MigrationGroup, error) {
+func (r *MigrationDiskRepo) GetSortedMigrations(ctx context.Context, version *domain.VersionDb) (*domain.MigrationGroup, error) {
 	versionString := version.String()
-	migrationFiles, err := helper.GetFilesInDir(r.PathToMigrations + "/" + versionString)
+	migrationFiles, err := helper.GetFilesInDir(r.MigrationsFS, versionString)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domain.WithKind(domain.ErrNotFound, err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	migrations := make([]domain.Migration, 0)
+	// The key is the name of the migration
+	directivesOfDown := make(map[string]directives)
 	for _, file := range migrationFiles {
 		name := file.Name()
 		extension := filepath.Ext(name)
 		const correctExtension string = ".sql"
 		if extension != correctExtension {
-			helper.ShowIfVerbose(r.IsVerbose, fmt.Sprintf("%s %s is ignored because of a bad file extension.", versionString, name))
+			r.printer.ShowIfVerbose(fmt.Sprintf("%s %s is ignored because of a bad file extension.", versionString, name))
 			continue
 		}
 
 		nameWithoutExtension := name[:len(name)-len(extension)]
+		if strings.HasSuffix(nameWithoutExtension, downSuffix) {
+			downSql, err := helper.ReadFile(r.MigrationsFS, versionString+"/"+name)
+			if err != nil {
+				return nil, err
+			}
+			directivesOfDown[strings.TrimSuffix(nameWithoutExtension, downSuffix)] = parseDirectives(downSql)
+			continue
+		}
+
 		newMigration, err := domain.NewMigration(nameWithoutExtension, version)
 		if err != nil {
-			helper.ShowIfVerbose(r.IsVerbose, fmt.Sprintf("%s %s is ignored, err: %s", versionString, name, err))
+			r.printer.ShowIfVerbose(fmt.Sprintf("%s %s is ignored, err: %s", versionString, name, err))
 			continue
 		}
+
+		sql, err := r.GetSqlFromMigration(ctx, newMigration)
+		if err != nil {
+			return nil, err
+		}
+		directives := parseDirectives(sql)
+		newMigration.IsNoTransaction = directives.isNoTransaction
+
 		migrations = append(migrations, *newMigration)
 	}
 
+	for i := range migrations {
+		downDirectives, hasDown := directivesOfDown[migrations[i].Name]
+		migrations[i].HasDown = hasDown
+		migrations[i].IsDownNoTransaction = downDirectives.isNoTransaction
+		delete(directivesOfDown, migrations[i].Name)
+	}
+	for name := range directivesOfDown {
+		r.printer.ShowIfVerbose(fmt.Sprintf("%s %s%s.sql is ignored, there is no migration for it.", versionString, name, downSuffix))
+	}
+
 	mg := domain.NewMigrationGroup(version, migrations)
 	if err := migrationsStructureCheck(mg); err != nil {
 		return nil, err
@@ -60,7 +95,10 @@ func (r *MigrationDiskRepo) GetSortedMigrations(_ context.Context, version *doma
 
 // Returns sorted versionDb
 func (r *MigrationDiskRepo) GetSortedVersions(_ context.Context) ([]*domain.VersionDb, error) {
-	versionDirs, err := helper.GetFilesInDir(r.PathToMigrations)
+	versionDirs, err := helper.GetFilesInDir(r.MigrationsFS, ".")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domain.WithKind(domain.ErrNotFound, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +108,7 @@ func (r *MigrationDiskRepo) GetSortedVersions(_ context.Context) ([]*domain.Vers
 		if helper.IsFirstV(nameDir) {
 			v, err := domain.NewVersionDb(nameDir)
 			if err != nil {
-				helper.ShowIfVerbose(r.IsVerbose, fmt.Sprintf("%s is ignored. %v", nameDir, err))
+				r.printer.ShowIfVerbose(fmt.Sprintf("%s is ignored. %v", nameDir, err))
 				continue
 			}
 
@@ -81,16 +119,61 @@ func (r *MigrationDiskRepo) GetSortedVersions(_ context.Context) ([]*domain.Vers
 	return versions, nil
 }
 
+// Returns domain.ErrNotFound if there is no migration file
 func (r *MigrationDiskRepo) GetSqlFromMigration(_ context.Context, migration *domain.Migration) (string, error) {
-	const extensionForMigrationFiles = "sql"
-	pathToSqlFile := fmt.Sprintf("%s/%s/%s.%s", r.PathToMigrations, migration.VersionDb.String(), migration.Name, extensionForMigrationFiles)
-	sql, err := helper.ReadFile(pathToSqlFile)
+	pathToSqlFile := fmt.Sprintf("%s/%s.%s", migration.VersionDb.String(), migration.Name, extensionForMigrationFiles)
+	sql, err := helper.ReadFile(r.MigrationsFS, pathToSqlFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%s: %w", pathToSqlFile, domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", err
+	}
+	return sql, nil
+}
+
+// Returns the script that reverts the migration. Example: 0003.CreateTest1.down.sql
+func (r *MigrationDiskRepo) GetSqlFromDownMigration(_ context.Context, migration *domain.Migration) (string, error) {
+	pathToSqlFile := fmt.Sprintf("%s/%s%s.%s", migration.VersionDb.String(), migration.Name, downSuffix, extensionForMigrationFiles)
+	sql, err := helper.ReadFile(r.MigrationsFS, pathToSqlFile)
 	if err != nil {
 		return "", err
 	}
 	return sql, nil
 }
 
+const extensionForMigrationFiles = "sql"
+
+// The down script is placed next to the migration. Example: 0003.CreateTest1.sql and 0003.CreateTest1.down.sql
+const downSuffix = ".down"
+
+// Directives are set in the comments at the beginning of the migration file
+const directiveNoTransaction = "dbupdater:no-transaction"
+
+type directives struct {
+	isNoTransaction bool
+}
+
+// Reads directives from the comments before the first statement. Example: -- dbupdater:no-transaction
+func parseDirectives(sql string) directives {
+	result := directives{}
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		directive := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		if directive == directiveNoTransaction {
+			result.isNoTransaction = true
+		}
+	}
+	return result
+}
+
 // Checking the order of migrations in one database version
 func migrationsStructureCheck(mg *domain.MigrationGroup) error {
 	for i, migration := range mg.Migrations {
@@ -0,0 +1,353 @@
+package migration_postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	operationApply    = "apply"
+	operationImport   = "import"
+	operationBaseline = "baseline"
+
+	// The operations of the manual changes are the kinds of domain.ManualChange
+	operationRollback = "rollback"
+)
+
+// Columns that appeared in later versions of dbupdater. They are added to the tables created earlier.
+var columnsAddedToHistoryTable = []string{
+	"reverted_version_db varchar",
+	"reverted_name varchar",
+	"reason varchar",
+	"changed_by varchar",
+	"client_host varchar",
+}
+
+// The defaults of the added columns are set separately, so the existing rows are not filled with them
+var defaultsOfColumnsAddedToHistoryTable = []string{
+	"client_host SET DEFAULT host(inet_client_addr())",
+}
+
+// HistoryPostgresRepo tracks applied migrations in the table owned by dbupdater. One row per applied migration.
+// The table is created automatically when the first row is written.
+// The version_db and name of the last row is the current migration. For the rollback rows it is the migration
+// that became current after the revert, the reverted migration is in reverted_version_db and reverted_name.
+type HistoryPostgresRepo struct {
+	conn        *pgx.Conn
+	toolVersion string
+
+	// The name as it was specified by the user. Example: public.dbupdater_history
+	tableName string
+
+	// The name prepared for inserting into the sql text
+	sanitizedTableName string
+}
+
+func NewHistoryPostgresRepo(conn *pgx.Conn, tableName string, toolVersion string) *HistoryPostgresRepo {
+	return &HistoryPostgresRepo{
+		conn:               conn,
+		toolVersion:        toolVersion,
+		tableName:          tableName,
+		sanitizedTableName: pgx.Identifier(strings.Split(tableName, ".")).Sanitize(),
+	}
+}
+
+// Checks that the history table exists and has at least one row
+func (mRepo *HistoryPostgresRepo) HasCurrentMigration(ctx context.Context) (bool, error) {
+	isExistsTable, err := mRepo.isExistsTable(ctx)
+	if err != nil || !isExistsTable {
+		return false, err
+	}
+
+	isAvailable := false
+	sql := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s)", mRepo.sanitizedTableName)
+	if err := mRepo.conn.QueryRow(ctx, sql).Scan(&isAvailable); err != nil {
+		return false, err
+	}
+	return isAvailable, nil
+}
+
+// Returns the migration from the last row of the history
+func (mRepo *HistoryPostgresRepo) GetCurrentMigration(ctx context.Context) (*domain.Migration, error) {
+	sql := fmt.Sprintf("SELECT version_db, name FROM %s ORDER BY id DESC LIMIT 1", mRepo.sanitizedTableName)
+	row, err := mRepo.conn.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer row.Close()
+
+	migrationFromDb, err := pgx.CollectOneRow(row, pgx.RowToStructByName[migration])
+	if err != nil {
+		return nil, fmt.Errorf("error when reading the last row of %s: %w", mRepo.tableName, err)
+	}
+	migration, err := migrationRepoToDomain(&migrationFromDb)
+	if err != nil {
+		return nil, fmt.Errorf("migrationRepoToDomain failed: %w", err)
+	}
+	return migration, nil
+}
+
+// Returns the last application of each migration that was applied or marked as applied with a checksum.
+// Migrations imported from the legacy mode have no checksum. The reverted migrations are also returned.
+func (mRepo *HistoryPostgresRepo) GetAppliedMigrations(ctx context.Context) ([]domain.AppliedMigration, error) {
+	isExistsTable, err := mRepo.isExistsTable(ctx)
+	if err != nil || !isExistsTable {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("SELECT DISTINCT ON (version_db, name) version_db, name, checksum, applied_at, "+
+		"COALESCE(duration_ms, 0) AS duration_ms FROM %s WHERE operation IN ($1, $2) AND checksum IS NOT NULL "+
+		"ORDER BY version_db, name, id DESC", mRepo.sanitizedTableName)
+	rows, err := mRepo.conn.Query(ctx, sql, operationApply, domain.ManualChangeMarkApplied)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedMigrationsFromDb, err := pgx.CollectRows(rows, pgx.RowToStructByName[appliedMigration])
+	if err != nil {
+		return nil, fmt.Errorf("error when reading applied migrations from %s: %w", mRepo.tableName, err)
+	}
+	appliedMigrations := make([]domain.AppliedMigration, 0, len(appliedMigrationsFromDb))
+	for i := range appliedMigrationsFromDb {
+		appliedMigration, err := appliedMigrationRepoToDomain(&appliedMigrationsFromDb[i])
+		if err != nil {
+			return nil, fmt.Errorf("appliedMigrationRepoToDomain failed: %w", err)
+		}
+		appliedMigrations = append(appliedMigrations, *appliedMigration)
+	}
+	return appliedMigrations, nil
+}
+
+// Returns the migrations whose last application was recorded with skip. The migrations applied after the skip are not returned.
+func (mRepo *HistoryPostgresRepo) GetSkippedMigrations(ctx context.Context) ([]domain.Migration, error) {
+	isExistsTable, err := mRepo.isExistsTable(ctx)
+	if err != nil || !isExistsTable {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("SELECT version_db, name FROM (SELECT DISTINCT ON (version_db, name) version_db, name, operation "+
+		"FROM %s WHERE operation IN ($1, $2, $3) ORDER BY version_db, name, id DESC) last WHERE operation = $3",
+		mRepo.sanitizedTableName)
+	rows, err := mRepo.conn.Query(ctx, sql, operationApply, domain.ManualChangeMarkApplied, domain.ManualChangeSkip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	migrationsFromDb, err := pgx.CollectRows(rows, pgx.RowToStructByName[migration])
+	if err != nil {
+		return nil, fmt.Errorf("error when reading the skipped migrations from %s: %w", mRepo.tableName, err)
+	}
+	skippedMigrations := make([]domain.Migration, 0, len(migrationsFromDb))
+	for i := range migrationsFromDb {
+		skippedMigration, err := migrationRepoToDomain(&migrationsFromDb[i])
+		if err != nil {
+			return nil, fmt.Errorf("migrationRepoToDomain failed: %w", err)
+		}
+		skippedMigrations = append(skippedMigrations, *skippedMigration)
+	}
+	return skippedMigrations, nil
+}
+
+// Returns the rows of the history recorded from since inclusive to until exclusive in the order of recording.
+// The zero times do not limit. Returns nothing if there is no history table.
+func (mRepo *HistoryPostgresRepo) GetHistory(ctx context.Context, since time.Time, until time.Time) ([]domain.HistoryRecord, error) {
+	isExistsTable, err := mRepo.isExistsTable(ctx)
+	if err != nil || !isExistsTable {
+		return nil, err
+	}
+
+	addedColumns, err := mRepo.selectAddedColumns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading the columns of %s: %w", mRepo.tableName, err)
+	}
+	sql := fmt.Sprintf("SELECT version_db, name, operation, COALESCE(checksum, '') AS checksum, applied_at, "+
+		"COALESCE(duration_ms, 0) AS duration_ms, applied_by, COALESCE(tool_version, '') AS tool_version, %s "+
+		"FROM %s WHERE applied_at >= $1 AND applied_at < $2 ORDER BY id", addedColumns, mRepo.sanitizedTableName)
+	rows, err := mRepo.conn.Query(ctx, sql, timeOrInfinity(since, pgtype.NegativeInfinity), timeOrInfinity(until, pgtype.Infinity))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	historyRows, err := pgx.CollectRows(rows, pgx.RowToStructByName[historyRow])
+	if err != nil {
+		return nil, fmt.Errorf("error when reading the history from %s: %w", mRepo.tableName, err)
+	}
+	records := make([]domain.HistoryRecord, 0, len(historyRows))
+	for i := range historyRows {
+		record, err := historyRowRepoToDomain(&historyRows[i])
+		if err != nil {
+			return nil, fmt.Errorf("historyRowRepoToDomain failed: %w", err)
+		}
+		records = append(records, *record)
+	}
+	return records, nil
+}
+
+// Adds a row to the history for each applied migration
+func (mRepo *HistoryPostgresRepo) UpdateCurrentMigration(ctx context.Context, appliedMigrations []domain.AppliedMigration) (err error) {
+	if err := mRepo.createTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (version_db, name, operation, checksum, applied_at, duration_ms, tool_version) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7)", mRepo.sanitizedTableName)
+	for _, appliedMigration := range appliedMigrations {
+		migration := migrationDomainToRepo(appliedMigration.Migration)
+		if _, err := mRepo.conn.Exec(ctx, sql, migration.VersionDb, migration.Name, operationApply, appliedMigration.Checksum,
+			appliedMigration.AppliedAt, appliedMigration.Duration.Milliseconds(), mRepo.toolVersion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds a row about the migration that was applied before the history table appeared
+func (mRepo *HistoryPostgresRepo) ImportCurrentMigration(ctx context.Context, currentMigration *domain.Migration) error {
+	if err := mRepo.createTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	migration := migrationDomainToRepo(currentMigration)
+	sql := fmt.Sprintf("INSERT INTO %s (version_db, name, operation, tool_version) VALUES ($1, $2, $3, $4)", mRepo.sanitizedTableName)
+	if _, err := mRepo.conn.Exec(ctx, sql, migration.VersionDb, migration.Name, operationImport, mRepo.toolVersion); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Adds a row for each change, the migration of the last change becomes the current one
+func (mRepo *HistoryPostgresRepo) RecordManualChanges(ctx context.Context, changes []domain.ManualChange) error {
+	if err := mRepo.createTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (version_db, name, operation, checksum, applied_at, tool_version, reason, changed_by) "+
+		"VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)", mRepo.sanitizedTableName)
+	for _, change := range changes {
+		migration := migrationDomainToRepo(change.Migration)
+		if _, err := mRepo.conn.Exec(ctx, sql, migration.VersionDb, migration.Name, change.Kind, change.Checksum,
+			change.ChangedAt, mRepo.toolVersion, change.Reason, change.ChangedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds a row about the migration up to which the database existed before dbupdater, nothing is executed
+func (mRepo *HistoryPostgresRepo) BaselineCurrentMigration(ctx context.Context, currentMigration *domain.Migration) error {
+	if err := mRepo.createTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	migration := migrationDomainToRepo(currentMigration)
+	sql := fmt.Sprintf("INSERT INTO %s (version_db, name, operation, tool_version) VALUES ($1, $2, $3, $4)", mRepo.sanitizedTableName)
+	if _, err := mRepo.conn.Exec(ctx, sql, migration.VersionDb, migration.Name, operationBaseline, mRepo.toolVersion); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Adds a row to the history for each reverted migration
+func (mRepo *HistoryPostgresRepo) RevertCurrentMigration(ctx context.Context, revertedMigrations []domain.RevertedMigration) (err error) {
+	if err := mRepo.createTableIfNotExists(ctx); err != nil {
+		return err
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (version_db, name, operation, applied_at, duration_ms, tool_version, "+
+		"reverted_version_db, reverted_name) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", mRepo.sanitizedTableName)
+	for _, revertedMigration := range revertedMigrations {
+		current := migrationDomainToRepo(revertedMigration.CurrentMigration)
+		reverted := migrationDomainToRepo(revertedMigration.Migration)
+		if _, err := mRepo.conn.Exec(ctx, sql, current.VersionDb, current.Name, operationRollback, revertedMigration.RevertedAt,
+			revertedMigration.Duration.Milliseconds(), mRepo.toolVersion, reverted.VersionDb, reverted.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the select list of the columns from columnsAddedToHistoryTable. The columns missing in the tables
+// created by older versions of dbupdater are selected as empty strings.
+func (mRepo *HistoryPostgresRepo) selectAddedColumns(ctx context.Context) (string, error) {
+	sql := "SELECT attname FROM pg_attribute WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped"
+	rows, err := mRepo.conn.Query(ctx, sql, mRepo.sanitizedTableName)
+	if err != nil {
+		return "", err
+	}
+	existingColumns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", err
+	}
+	isExistingColumn := make(map[string]bool, len(existingColumns))
+	for _, name := range existingColumns {
+		isExistingColumn[name] = true
+	}
+
+	selectList := make([]string, 0, len(columnsAddedToHistoryTable))
+	for _, column := range columnsAddedToHistoryTable {
+		name := strings.Fields(column)[0]
+		if isExistingColumn[name] {
+			selectList = append(selectList, fmt.Sprintf("COALESCE(%s, '') AS %s", name, name))
+		} else {
+			selectList = append(selectList, fmt.Sprintf("'' AS %s", name))
+		}
+	}
+	return strings.Join(selectList, ", "), nil
+}
+
+// The zero time is replaced with the infinity, so the condition on it is always true
+func timeOrInfinity(t time.Time, infinity pgtype.InfinityModifier) pgtype.Timestamptz {
+	if t.IsZero() {
+		return pgtype.Timestamptz{InfinityModifier: infinity, Valid: true}
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+func (mRepo *HistoryPostgresRepo) isExistsTable(ctx context.Context) (bool, error) {
+	isExists := false
+	if err := mRepo.conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", mRepo.sanitizedTableName).Scan(&isExists); err != nil {
+		return false, err
+	}
+	return isExists, nil
+}
+
+func (mRepo *HistoryPostgresRepo) createTableIfNotExists(ctx context.Context) error {
+	sql := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id bigserial PRIMARY KEY,
+		version_db varchar NOT NULL,
+		name varchar NOT NULL,
+		operation varchar NOT NULL,
+		checksum varchar,
+		applied_at timestamptz NOT NULL DEFAULT now(),
+		duration_ms bigint,
+		applied_by varchar NOT NULL DEFAULT current_user,
+		tool_version varchar
+	)`, mRepo.sanitizedTableName)
+	if _, err := mRepo.conn.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("error when creating the history table %s: %w", mRepo.tableName, err)
+	}
+
+	for _, column := range columnsAddedToHistoryTable {
+		sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", mRepo.sanitizedTableName, column)
+		if _, err := mRepo.conn.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("error when adding the column to the history table %s: %w", mRepo.tableName, err)
+		}
+	}
+	for _, columnDefault := range defaultsOfColumnsAddedToHistoryTable {
+		sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", mRepo.sanitizedTableName, columnDefault)
+		if _, err := mRepo.conn.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("error when setting the default of the column of the history table %s: %w", mRepo.tableName, err)
+		}
+	}
+	return nil
+}
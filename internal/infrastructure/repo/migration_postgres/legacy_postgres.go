@@ -0,0 +1,126 @@
+package migration_postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awsoremod/DBUpdater/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Returns the text of a sql query. Used to read user files with queries only when they are needed.
+type SqlSource func() (string, error)
+
+// LegacyPostgresRepo tracks the current migration with the help of user queries from the utils/*.sql files
+type LegacyPostgresRepo struct {
+	conn *pgx.Conn
+
+	// If nil, it is considered that the current migration is always available
+	sqlForCheckMigration  SqlSource
+	sqlForGetMigration    SqlSource
+	sqlForUpdateMigration SqlSource
+}
+
+func NewLegacyPostgresRepo(conn *pgx.Conn, sqlForCheckMigration SqlSource, sqlForGetMigration SqlSource,
+	sqlForUpdateMigration SqlSource,
+) *LegacyPostgresRepo {
+	return &LegacyPostgresRepo{
+		conn:                  conn,
+		sqlForCheckMigration:  sqlForCheckMigration,
+		sqlForGetMigration:    sqlForGetMigration,
+		sqlForUpdateMigration: sqlForUpdateMigration,
+	}
+}
+
+func (mRepo *LegacyPostgresRepo) HasCurrentMigration(ctx context.Context) (bool, error) {
+	if mRepo.sqlForCheckMigration == nil {
+		return true, nil
+	}
+	sqlForCheckMigration, err := mRepo.sqlForCheckMigration()
+	if err != nil {
+		return false, err
+	}
+
+	isAvailable := false
+	if err := mRepo.conn.QueryRow(ctx, sqlForCheckMigration).Scan(&isAvailable); err != nil {
+		return false, err
+	}
+	return isAvailable, nil
+}
+
+// Returns the last applied migration by executing a sql query
+func (mRepo *LegacyPostgresRepo) GetCurrentMigration(ctx context.Context) (*domain.Migration, error) {
+	sqlForGetMigration, err := mRepo.sqlForGetMigration()
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := mRepo.conn.Query(ctx, sqlForGetMigration)
+	if err != nil {
+		return nil, err
+	}
+	defer row.Close()
+
+	migrationFromDb, err := pgx.CollectOneRow(row, pgx.RowToStructByName[migration])
+	if err != nil {
+		return nil, err
+	}
+	migration, err := migrationRepoToDomain(&migrationFromDb)
+	if err != nil {
+		return nil, fmt.Errorf("migrationRepoToDomain failed: %w", err)
+	}
+	return migration, nil
+}
+
+// Only the last applied migration is saved
+func (mRepo *LegacyPostgresRepo) UpdateCurrentMigration(ctx context.Context, appliedMigrations []domain.AppliedMigration) (err error) {
+	if len(appliedMigrations) == 0 {
+		return nil
+	}
+	sqlForUpdateMigration, err := mRepo.sqlForUpdateMigration()
+	if err != nil {
+		return err
+	}
+
+	migration := migrationDomainToRepo(appliedMigrations[len(appliedMigrations)-1].Migration)
+	// Example sqlForUpdateCurrentVersion: UPDATE lastMigration SET version_db=$1, name=$2;
+	if _, err := mRepo.conn.Exec(ctx, sqlForUpdateMigration, migration.VersionDb, migration.Name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Only the migration of the last change is saved, the reason cannot be saved
+func (mRepo *LegacyPostgresRepo) RecordManualChanges(ctx context.Context, changes []domain.ManualChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	sqlForUpdateMigration, err := mRepo.sqlForUpdateMigration()
+	if err != nil {
+		return err
+	}
+
+	migration := migrationDomainToRepo(changes[len(changes)-1].Migration)
+	if _, err := mRepo.conn.Exec(ctx, sqlForUpdateMigration, migration.VersionDb, migration.Name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Only the migration that became current after the last revert is saved
+func (mRepo *LegacyPostgresRepo) RevertCurrentMigration(ctx context.Context, revertedMigrations []domain.RevertedMigration) (err error) {
+	if len(revertedMigrations) == 0 {
+		return nil
+	}
+	sqlForUpdateMigration, err := mRepo.sqlForUpdateMigration()
+	if err != nil {
+		return err
+	}
+
+	migration := migrationDomainToRepo(revertedMigrations[len(revertedMigrations)-1].CurrentMigration)
+	if _, err := mRepo.conn.Exec(ctx, sqlForUpdateMigration, migration.VersionDb, migration.Name); err != nil {
+		return err
+	}
+	return nil
+}
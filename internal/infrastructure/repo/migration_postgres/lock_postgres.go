@@ -0,0 +1,78 @@
+package migration_postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/awsoremod/DBUpdater/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LockPostgresRepo takes session-level advisory locks. The lock is held until it is released or the connection is closed,
+// committing or rolling back transactions does not release it.
+type LockPostgresRepo struct {
+	conn *pgx.Conn
+}
+
+func NewLockPostgresRepo(conn *pgx.Conn) *LockPostgresRepo {
+	return &LockPostgresRepo{
+		conn: conn,
+	}
+}
+
+// Returns false if the lock is held by another session
+func (lRepo *LockPostgresRepo) TryLock(ctx context.Context, lock *domain.Lock) (bool, error) {
+	isLocked := false
+	if err := lRepo.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lock.Key()).Scan(&isLocked); err != nil {
+		return false, err
+	}
+	return isLocked, nil
+}
+
+func (lRepo *LockPostgresRepo) Unlock(ctx context.Context, lock *domain.Lock) error {
+	isUnlocked := false
+	if err := lRepo.conn.QueryRow(ctx, "SELECT pg_advisory_unlock($1)", lock.Key()).Scan(&isUnlocked); err != nil {
+		return err
+	}
+	if !isUnlocked {
+		return errors.New("the lock was not held")
+	}
+	return nil
+}
+
+// Returns true if another session holds the lock in another database. dbupdater holds it in the database 'postgres'
+// while the locked database is restored from a dump.
+func (lRepo *LockPostgresRepo) IsHeldForRestore(ctx context.Context, lock *domain.Lock) (bool, error) {
+	sql := `SELECT EXISTS (SELECT 1 FROM pg_locks l
+		WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+			AND l.classid = (($1::bigint >> 32) & 4294967295)::oid AND l.objid = ($1::bigint & 4294967295)::oid
+			AND l.pid <> pg_backend_pid()
+			AND l.database <> (SELECT oid FROM pg_database WHERE datname = current_database()))`
+
+	isHeld := false
+	if err := lRepo.conn.QueryRow(ctx, sql, lock.Key()).Scan(&isHeld); err != nil {
+		return false, err
+	}
+	return isHeld, nil
+}
+
+// Returns nil if the lock is not held
+func (lRepo *LockPostgresRepo) GetLockHolder(ctx context.Context, lock *domain.Lock) (*domain.LockHolder, error) {
+	// A bigint key is stored in pg_locks as two halves: the high one in classid, the low one in objid
+	sql := `SELECT a.pid, coalesce(a.application_name, ''), coalesce(host(a.client_addr), ''), a.backend_start
+		FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+			AND l.classid = (($1::bigint >> 32) & 4294967295)::oid AND l.objid = ($1::bigint & 4294967295)::oid
+		LIMIT 1`
+
+	holder := &domain.LockHolder{}
+	err := lRepo.conn.QueryRow(ctx, sql, lock.Key()).Scan(&holder.Pid, &holder.ApplicationName, &holder.ClientAddr, &holder.BackendStart)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return holder, nil
+}
@@ -1,7 +1,9 @@
 package migration_postgres
 
 import (
-	"dbupdater/internal/domain"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/internal/domain"
 )
 
 type migration struct {
@@ -26,3 +28,84 @@ func migrationDomainToRepo(m *domain.Migration) *migration {
 		Name:      m.Name,
 	}
 }
+
+// A row of the history table about an applied migration
+type appliedMigration struct {
+	VersionDb  string    `db:"version_db"`
+	Name       string    `db:"name"`
+	Checksum   string    `db:"checksum"`
+	AppliedAt  time.Time `db:"applied_at"`
+	DurationMs int64     `db:"duration_ms"`
+}
+
+func appliedMigrationRepoToDomain(m *appliedMigration) (*domain.AppliedMigration, error) {
+	migration, err := migrationRepoToDomain(&migration{VersionDb: m.VersionDb, Name: m.Name})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AppliedMigration{
+		Migration: migration,
+		Checksum:  m.Checksum,
+		AppliedAt: m.AppliedAt,
+		Duration:  time.Duration(m.DurationMs) * time.Millisecond,
+	}, nil
+}
+
+// A row of the history table. The empty values are read as empty strings.
+type historyRow struct {
+	VersionDb         string    `db:"version_db"`
+	Name              string    `db:"name"`
+	Operation         string    `db:"operation"`
+	Checksum          string    `db:"checksum"`
+	AppliedAt         time.Time `db:"applied_at"`
+	DurationMs        int64     `db:"duration_ms"`
+	AppliedBy         string    `db:"applied_by"`
+	ClientHost        string    `db:"client_host"`
+	ToolVersion       string    `db:"tool_version"`
+	RevertedVersionDb string    `db:"reverted_version_db"`
+	RevertedName      string    `db:"reverted_name"`
+	Reason            string    `db:"reason"`
+	ChangedBy         string    `db:"changed_by"`
+}
+
+func historyRowRepoToDomain(row *historyRow) (*domain.HistoryRecord, error) {
+	currentMigration, err := migrationRepoToDomain(&migration{VersionDb: row.VersionDb, Name: row.Name})
+	if err != nil {
+		return nil, err
+	}
+	record := &domain.HistoryRecord{
+		Migration:        currentMigration,
+		CurrentMigration: currentMigration,
+		Outcome:          outcomeOfOperation(row.Operation),
+		At:               row.AppliedAt,
+		Duration:         time.Duration(row.DurationMs) * time.Millisecond,
+		DbUser:           row.AppliedBy,
+		ClientHost:       row.ClientHost,
+		ToolVersion:      row.ToolVersion,
+		Checksum:         row.Checksum,
+		Reason:           row.Reason,
+		ChangedBy:        row.ChangedBy,
+	}
+	if row.Operation == operationRollback {
+		record.Migration, err = migrationRepoToDomain(&migration{VersionDb: row.RevertedVersionDb, Name: row.RevertedName})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// The operations of the manual changes are equal to the outcomes
+func outcomeOfOperation(operation string) string {
+	switch operation {
+	case operationApply:
+		return domain.HistoryOutcomeApplied
+	case operationRollback:
+		return domain.HistoryOutcomeRolledBack
+	case operationImport:
+		return domain.HistoryOutcomeImported
+	case operationBaseline:
+		return domain.HistoryOutcomeBaseline
+	}
+	return operation
+}
@@ -3,14 +3,16 @@ package migration_postgres
 import (
 	"context"
 	"fmt"
-
-	"dbupdater/internal/domain"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 )
 
 type MigrationPostgresRepo struct {
 	conn *pgx.Conn
+
+	// Not nil while the transaction is open
+	tx pgx.Tx
 }
 
 func NewMigrationPostgresRepo(conn *pgx.Conn) *MigrationPostgresRepo {
@@ -19,46 +21,53 @@ func NewMigrationPostgresRepo(conn *pgx.Conn) *MigrationPostgresRepo {
 	}
 }
 
-func (mRepo *MigrationPostgresRepo) HasCurrentMigration(ctx context.Context, sqlForCheckMigration string) (bool, error) {
-	isAvailable := false
-	err := mRepo.conn.QueryRow(ctx, sqlForCheckMigration).Scan(&isAvailable)
-	if err != nil {
-		return false, err
+func (mRepo *MigrationPostgresRepo) ExecSql(ctx context.Context, sql string) (err error) {
+	if _, err := mRepo.conn.Exec(ctx, sql); err != nil {
+		return err
 	}
-	return isAvailable, nil
+	return nil
 }
 
-// Returns the last applied migration by executing a sql query
-func (mRepo *MigrationPostgresRepo) GetCurrentMigration(ctx context.Context, sqlForGetMigration string) (*domain.Migration, error) {
-	row, err := mRepo.conn.Query(ctx, sqlForGetMigration)
-	if err != nil {
-		return nil, err
+// Executes COPY ... FROM stdin with the rows in the text format of COPY
+func (mRepo *MigrationPostgresRepo) CopyFromStdin(ctx context.Context, sql string, data string) error {
+	if _, err := mRepo.conn.PgConn().CopyFrom(ctx, strings.NewReader(data), sql); err != nil {
+		return err
 	}
-	defer row.Close()
+	return nil
+}
 
-	migrationFromDb, err := pgx.CollectOneRow(row, pgx.RowToStructByName[migration])
-	if err != nil {
-		return nil, err
+func (mRepo *MigrationPostgresRepo) IsInTransaction() bool {
+	return mRepo.tx != nil
+}
+
+// After the beginning of the transaction, all queries on the connection are executed in it,
+// including queries of other repositories that use the same connection
+func (mRepo *MigrationPostgresRepo) BeginTransaction(ctx context.Context) error {
+	if mRepo.tx != nil {
+		return fmt.Errorf("the transaction has already begun")
 	}
-	migration, err := migrationRepoToDomain(&migrationFromDb)
+	tx, err := mRepo.conn.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("migrationRepoToDomain failed: %w", err)
+		return err
 	}
-	return migration, nil
+	mRepo.tx = tx
+	return nil
 }
 
-func (mRepo *MigrationPostgresRepo) UpdateCurrentMigration(ctx context.Context, sqlForUpdateMigration string, lastAppliedMigration *domain.Migration) (err error) {
-	migration := migrationDomainToRepo(lastAppliedMigration)
-	// Example sqlForUpdateCurrentVersion: UPDATE lastMigration SET version_db=$1, name=$2;
-	if _, err := mRepo.conn.Exec(ctx, sqlForUpdateMigration, migration.VersionDb, migration.Name); err != nil {
-		return err
+func (mRepo *MigrationPostgresRepo) CommitTransaction(ctx context.Context) error {
+	if mRepo.tx == nil {
+		return fmt.Errorf("there is no transaction to commit")
 	}
-	return nil
+	tx := mRepo.tx
+	mRepo.tx = nil
+	return tx.Commit(ctx)
 }
 
-func (mRepo *MigrationPostgresRepo) ExecSql(ctx context.Context, sql string) (err error) {
-	if _, err := mRepo.conn.Exec(ctx, sql); err != nil {
-		return err
+func (mRepo *MigrationPostgresRepo) RollbackTransaction(ctx context.Context) error {
+	if mRepo.tx == nil {
+		return fmt.Errorf("there is no transaction to roll back")
 	}
-	return nil
+	tx := mRepo.tx
+	mRepo.tx = nil
+	return tx.Rollback(ctx)
 }
@@ -0,0 +1,139 @@
+package plan_file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+// The version of the file format. Files of other versions are not read.
+const formatVersion = 1
+
+// PlanFileRepo stores the saved plans in JSON files
+type PlanFileRepo struct{}
+
+func NewPlanFileRepo() *PlanFileRepo {
+	return &PlanFileRepo{}
+}
+
+type planFile struct {
+	FormatVersion    int                `json:"format_version"`
+	CreatedAt        time.Time          `json:"created_at"`
+	IsInitMode       bool               `json:"is_init_mode"`
+	CurrentMigration migration          `json:"current_migration"`
+	Migrations       []plannedMigration `json:"migrations"`
+}
+
+type migration struct {
+	// Example: v0.0.1
+	VersionDb string `json:"version_db"`
+
+	// name - is the number + name of the migration. Example: 0001.InitMigration1
+	Name string `json:"name"`
+}
+
+type plannedMigration struct {
+	migration
+
+	// sha256 of the migration file content in hex
+	Checksum string `json:"checksum"`
+}
+
+// Writes the plan to the file, the existing file is overwritten
+func (r *PlanFileRepo) Save(_ context.Context, path string, plan *domain.SavedPlan) error {
+	content, err := json.MarshalIndent(planDomainToRepo(plan), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error when writing the plan to %s: %w", path, err)
+	}
+	return nil
+}
+
+// Returns domain.ErrNotFound if there is no file
+func (r *PlanFileRepo) Load(_ context.Context, path string) (*domain.SavedPlan, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", path, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file planFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("the plan %s is not a dbupdater plan: %w", path, err)
+	}
+	if file.FormatVersion != formatVersion {
+		return nil, fmt.Errorf("the plan %s has the format version %d, this dbupdater reads the version %d",
+			path, file.FormatVersion, formatVersion)
+	}
+	plan, err := planRepoToDomain(&file)
+	if err != nil {
+		return nil, fmt.Errorf("wrong migration in the plan %s: %w", path, err)
+	}
+	return plan, nil
+}
+
+func planDomainToRepo(plan *domain.SavedPlan) *planFile {
+	migrations := make([]plannedMigration, 0, len(plan.Migrations))
+	for _, pm := range plan.Migrations {
+		migrations = append(migrations, plannedMigration{
+			migration: *migrationDomainToRepo(pm.Migration),
+			Checksum:  pm.Checksum,
+		})
+	}
+	return &planFile{
+		FormatVersion:    formatVersion,
+		CreatedAt:        plan.CreatedAt,
+		IsInitMode:       plan.IsInitMode,
+		CurrentMigration: *migrationDomainToRepo(plan.StartMigration),
+		Migrations:       migrations,
+	}
+}
+
+func planRepoToDomain(file *planFile) (*domain.SavedPlan, error) {
+	startMigration, err := migrationRepoToDomain(&file.CurrentMigration)
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]domain.PlannedMigration, 0, len(file.Migrations))
+	for i := range file.Migrations {
+		m, err := migrationRepoToDomain(&file.Migrations[i].migration)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, domain.PlannedMigration{
+			Migration: m,
+			Checksum:  file.Migrations[i].Checksum,
+		})
+	}
+	return &domain.SavedPlan{
+		CreatedAt:      file.CreatedAt,
+		StartMigration: startMigration,
+		IsInitMode:     file.IsInitMode,
+		Migrations:     migrations,
+	}, nil
+}
+
+func migrationRepoToDomain(m *migration) (*domain.Migration, error) {
+	version, err := domain.NewVersionDb(m.VersionDb)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewMigration(m.Name, version)
+}
+
+func migrationDomainToRepo(m *domain.Migration) *migration {
+	return &migration{
+		VersionDb: m.VersionDb.String(),
+		Name:      m.Name,
+	}
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+type HistoryRepoForBaseline interface {
+	HasCurrentMigration(ctx context.Context) (bool, error)
+	BaselineCurrentMigration(ctx context.Context, migration *domain.Migration) error
+}
+
+// Adopts the database that existed before dbupdater: the migration is recorded as the current one,
+// so it and the migrations before it are considered applied without executing them
+type BaselineUseCase struct {
+	printer *helper.Printer
+	repo    HistoryRepoForBaseline
+}
+
+func NewBaselineUseCase(repo HistoryRepoForBaseline, printer *helper.Printer) *BaselineUseCase {
+	return &BaselineUseCase{
+		printer: printer,
+		repo:    repo,
+	}
+}
+
+// The baseline is possible only if there are no records in the history yet
+func (uc *BaselineUseCase) Baseline(ctx context.Context, migration *domain.Migration) error {
+	uc.printer.ShowIfVerbose("Checking that the migration history is empty...")
+	hasCurrentMigration, err := uc.repo.HasCurrentMigration(ctx)
+	if err != nil {
+		return err
+	}
+	if hasCurrentMigration {
+		return fmt.Errorf("the migration history already contains applied migrations, the baseline is possible only for an empty history")
+	}
+
+	uc.printer.ShowIfVerbose("The baseline migration is recorded...")
+	if err := uc.repo.BaselineCurrentMigration(ctx, migration); err != nil {
+		return err
+	}
+	uc.printer.ShowIfVerbose("The baseline migration has been recorded.")
+	return nil
+}
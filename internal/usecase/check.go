@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+// Compares the current migration with the migrations in the directory or with the version required by the application
+type CheckUseCase struct {
+	printer *helper.Printer
+	repo    MigrationRepoForMigrations
+}
+
+func NewCheckUseCase(repo MigrationRepoForMigrations, printer *helper.Printer) *CheckUseCase {
+	return &CheckUseCase{
+		printer: printer,
+		repo:    repo,
+	}
+}
+
+// Without requiredVersion the current migration must be the last migration in the directory.
+// With requiredVersion the version of the current migration must satisfy it, the database is behind
+// if a newer version in the directory satisfies it. In initialization mode the database is behind.
+func (uc *CheckUseCase) Check(ctx context.Context, isInitMode bool, currentMigration *domain.Migration,
+	requiredVersion *domain.VersionConstraint,
+) (*domain.CheckResult, error) {
+	sortedMigrationGroups, err := getAllSortedMigrations(ctx, uc.repo)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.CheckResult{}
+	if len(sortedMigrationGroups) != 0 {
+		lastGroup := sortedMigrationGroups[len(sortedMigrationGroups)-1]
+		result.LatestMigration = &lastGroup.Migrations[len(lastGroup.Migrations)-1]
+	}
+
+	if isInitMode {
+		result.State = domain.CheckStateBehind
+		result.Reason = "no migrations have been applied"
+		return result, nil
+	}
+	result.CurrentMigration = currentMigration
+
+	indexMigrationGroup, indexMigration := domain.IndicesMigrationInMigrationGroups(sortedMigrationGroups, currentMigration)
+	if indexMigrationGroup == -1 || indexMigration == -1 {
+		result.State = domain.CheckStateAheadOrUnknown
+		result.Reason = fmt.Sprintf("the %s %s migration is not in the migrations directory",
+			currentMigration.VersionDb.String(), currentMigration.Name)
+		return result, nil
+	}
+
+	if requiredVersion == nil {
+		if currentMigration.IsEqual(result.LatestMigration) {
+			result.State = domain.CheckStateMatch
+			return result, nil
+		}
+		result.State = domain.CheckStateBehind
+		result.Reason = fmt.Sprintf("the last migration in the directory is %s %s",
+			result.LatestMigration.VersionDb.String(), result.LatestMigration.Name)
+		return result, nil
+	}
+
+	if requiredVersion.IsSatisfiedBy(currentMigration.VersionDb) {
+		result.State = domain.CheckStateMatch
+		return result, nil
+	}
+	for _, mg := range sortedMigrationGroups[indexMigrationGroup+1:] {
+		if requiredVersion.IsSatisfiedBy(mg.VersionDb) {
+			result.State = domain.CheckStateBehind
+			result.Reason = fmt.Sprintf("the %s version does not satisfy %s, the newer %s version does",
+				currentMigration.VersionDb.String(), requiredVersion.String(), mg.VersionDb.String())
+			return result, nil
+		}
+	}
+	result.State = domain.CheckStateAheadOrUnknown
+	result.Reason = fmt.Sprintf("neither the %s version nor the newer versions in the directory satisfy %s",
+		currentMigration.VersionDb.String(), requiredVersion.String())
+	return result, nil
+}
@@ -6,60 +6,73 @@ import (
 	"os"
 	"path/filepath"
 
-	"dbupdater/helper"
-	"dbupdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
 )
 
 type DumpInfrastructure interface {
 	Create(ctx context.Context, pathForSaveDumps string) (*domain.Dump, error)
 	Restore(ctx context.Context, dump *domain.Dump) error
 	GetCommandToRestoreDump(dump *domain.Dump) (string, error)
+	GetDescriptionOfUtilities() string
 }
 
 type DumpUseCase struct {
-	isVerbose        bool
+	printer          *helper.Printer
 	pathForSaveDumps string
 	infrastructure   DumpInfrastructure
 }
 
-func NewDumpUseCase(infrastructure DumpInfrastructure, isVerbose bool) (*DumpUseCase, error) {
+func NewDumpUseCase(infrastructure DumpInfrastructure, printer *helper.Printer) (*DumpUseCase, error) {
 	pathForSaveDumps, err := getPathForSaveDumps()
 	if err != nil {
 		return nil, fmt.Errorf("failed to set the path to the directory where to save migrations: %w", err)
 	}
 
 	return &DumpUseCase{
-		isVerbose:        isVerbose,
+		printer:          printer,
 		pathForSaveDumps: pathForSaveDumps,
 		infrastructure:   infrastructure,
 	}, nil
 }
 
+// Returns which pg_dump and pg_restore would be used
+func (uc *DumpUseCase) GetDescriptionOfUtilities() string {
+	return uc.infrastructure.GetDescriptionOfUtilities()
+}
+
 func (uc *DumpUseCase) Create(ctx context.Context) (*domain.Dump, error) {
-	helper.ShowIfVerbose(uc.isVerbose, "Dump is created...")
+	uc.printer.Println(uc.infrastructure.GetDescriptionOfUtilities())
+	uc.printer.ShowIfVerbose("Dump is created...")
 	newDump, err := uc.infrastructure.Create(ctx, uc.pathForSaveDumps)
 	if err != nil {
 		return nil, err
 	}
-	helper.ShowIfVerbose(uc.isVerbose, "Dump created.")
+	uc.printer.ShowIfVerbose("Dump created.")
 	return newDump, nil
 }
 
 // There must be no connections to the database
 func (uc *DumpUseCase) RestoreDatabaseFromDumpAndDeleteDump(ctx context.Context, dump *domain.Dump) error {
-	fmt.Println("The database is being restored from the dump...")
-	if err := uc.infrastructure.Restore(ctx, dump); err != nil {
-		if err != nil {
-			return err
-		}
+	if err := uc.RestoreDatabaseFromDump(ctx, dump); err != nil {
+		return err
 	}
-	fmt.Println("The database from the dump has been restored.")
 
 	if err := os.Remove(dump.Path()); err != nil {
-		fmt.Println("Db recovery was successful, error in deleting dump file after recovery: %w", err)
+		uc.printer.Printf("Db recovery was successful, error in deleting dump file after recovery: %s\n", err)
 		return nil
 	}
-	helper.ShowIfVerbose(uc.isVerbose, "Dump deleted.")
+	uc.printer.ShowIfVerbose("Dump deleted.")
+	return nil
+}
+
+// There must be no connections to the database. The dump is kept.
+func (uc *DumpUseCase) RestoreDatabaseFromDump(ctx context.Context, dump *domain.Dump) error {
+	uc.printer.Println("The database is being restored from the dump...")
+	if err := uc.infrastructure.Restore(ctx, dump); err != nil {
+		return err
+	}
+	uc.printer.Println("The database from the dump has been restored.")
 	return nil
 }
 
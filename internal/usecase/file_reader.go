@@ -1,16 +1,17 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"strings"
 
-	"dbupdater/helper"
+	"github.com/awsoremod/DBUpdater/helper"
 )
 
 type FileReaderUseCase struct {
-	isVerbose        bool
-	pathToMigrations string
+	printer      *helper.Printer
+	migrationsFS fs.FS
 
 	NameDirForSystemSqlFile               string
 	ShortPathToHasCurrentMigrationFile    string
@@ -25,10 +26,10 @@ const (
 	shortPathToUpdateCurrentMigrationFile = nameDirForSystemSqlFile + "/UpdateCurrentVersion.sql"
 )
 
-func NewFileReaderUseCase(pathToMigrations string, isVerbose bool) *FileReaderUseCase {
+func NewFileReaderUseCase(migrationsFS fs.FS, printer *helper.Printer) *FileReaderUseCase {
 	return &FileReaderUseCase{
-		isVerbose:        isVerbose,
-		pathToMigrations: pathToMigrations,
+		printer:      printer,
+		migrationsFS: migrationsFS,
 
 		NameDirForSystemSqlFile:               nameDirForSystemSqlFile,
 		ShortPathToHasCurrentMigrationFile:    shortPathToHasCurrentMigrationFile,
@@ -70,28 +71,26 @@ func (uc *FileReaderUseCase) GetSqlFromUpdateCurrentMigrationFile() (string, err
 }
 
 func (uc *FileReaderUseCase) isExistsFile(shortPath string) bool {
-	path := fmt.Sprintf("%s/%s", uc.pathToMigrations, shortPath)
-
-	helper.ShowIfVerbose(uc.isVerbose, fmt.Sprintf("Checking for the presence of %s...", shortPath))
-	_, err := os.Stat(path)
+	uc.printer.ShowIfVerbose(fmt.Sprintf("Checking for the presence of %s...", shortPath))
+	_, err := fs.Stat(uc.migrationsFS, shortPath)
 	if err == nil {
-		helper.ShowIfVerbose(uc.isVerbose, fmt.Sprintf("The file %s is available.", shortPath))
+		uc.printer.ShowIfVerbose(fmt.Sprintf("The file %s is available.", shortPath))
 		return true
 	}
-	if os.IsNotExist(err) {
-		helper.ShowIfVerbose(uc.isVerbose, fmt.Sprintf("The file %s is not available.", shortPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		uc.printer.ShowIfVerbose(fmt.Sprintf("The file %s is not available.", shortPath))
 		return false
 	}
-	helper.ShowIfVerbose(uc.isVerbose, fmt.Sprintf("The file %s is not available, error when checking file.", shortPath))
+	uc.printer.ShowIfVerbose(fmt.Sprintf("The file %s is not available, error when checking file.", shortPath))
 	return false
 }
 
 func (uc *FileReaderUseCase) readSqlFile(shortPath string) (string, error) {
-	helper.ShowIfVerbose(uc.isVerbose, fmt.Sprintf("The %s file is being read...", shortPath))
-	sqlFromFile, err := helper.ReadFile(uc.pathToMigrations + "/" + shortPath)
+	uc.printer.ShowIfVerbose(fmt.Sprintf("The %s file is being read...", shortPath))
+	sqlFromFile, err := helper.ReadFile(uc.migrationsFS, shortPath)
 	if err != nil {
 		return "", fmt.Errorf("error reading %s: %w", shortPath, err)
 	}
-	helper.ShowIfVerbose(uc.isVerbose, fmt.Sprintf("The %s file has been successfully read.", shortPath))
+	uc.printer.ShowIfVerbose(fmt.Sprintf("The %s file has been successfully read.", shortPath))
 	return sqlFromFile, nil
 }
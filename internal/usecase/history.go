@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+type HistoryRepoForHistory interface {
+	// Returns the records from since inclusive to until exclusive in the order of recording, the zero times do not limit
+	GetHistory(ctx context.Context, since time.Time, until time.Time) ([]domain.HistoryRecord, error)
+}
+
+// Reads what was applied, reverted or changed by hand, when and by whom
+type HistoryUseCase struct {
+	printer *helper.Printer
+	repo    HistoryRepoForHistory
+}
+
+func NewHistoryUseCase(repo HistoryRepoForHistory, printer *helper.Printer) *HistoryUseCase {
+	return &HistoryUseCase{
+		printer: printer,
+		repo:    repo,
+	}
+}
+
+// Returns the records that satisfy the filter in the order of recording
+func (uc *HistoryUseCase) GetHistory(ctx context.Context, filter *domain.HistoryFilter) ([]domain.HistoryRecord, error) {
+	uc.printer.ShowIfVerbose("The migration history is read...")
+	records, err := uc.repo.GetHistory(ctx, filter.Since, filter.Until)
+	if err != nil {
+		return nil, err
+	}
+
+	filteredRecords := make([]domain.HistoryRecord, 0, len(records))
+	for i := range records {
+		if filter.IsSatisfiedBy(&records[i]) {
+			filteredRecords = append(filteredRecords, records[i])
+		}
+	}
+	uc.printer.ShowIfVerbose(fmt.Sprintf("%d of %d records of the history are shown.", len(filteredRecords), len(records)))
+	return filteredRecords, nil
+}
@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+type HistoryRepoForImportLegacy interface {
+	HasCurrentMigration(ctx context.Context) (bool, error)
+	ImportCurrentMigration(ctx context.Context, migration *domain.Migration) error
+}
+
+// Transfers the current migration from the legacy utils/*.sql tracking to the history table
+type ImportLegacyUseCase struct {
+	printer *helper.Printer
+	repo    HistoryRepoForImportLegacy
+}
+
+func NewImportLegacyUseCase(repo HistoryRepoForImportLegacy, printer *helper.Printer) *ImportLegacyUseCase {
+	return &ImportLegacyUseCase{
+		printer: printer,
+		repo:    repo,
+	}
+}
+
+// The import is possible only if there are no records in the history yet
+func (uc *ImportLegacyUseCase) Import(ctx context.Context, legacyCurrentMigration *domain.Migration) error {
+	uc.printer.ShowIfVerbose("Checking that the migration history is empty...")
+	hasCurrentMigration, err := uc.repo.HasCurrentMigration(ctx)
+	if err != nil {
+		return err
+	}
+	if hasCurrentMigration {
+		return fmt.Errorf("the migration history already contains applied migrations, the import is possible only into an empty history")
+	}
+
+	uc.printer.ShowIfVerbose("The current migration is imported...")
+	if err := uc.repo.ImportCurrentMigration(ctx, legacyCurrentMigration); err != nil {
+		return err
+	}
+	uc.printer.ShowIfVerbose("The current migration has been imported.")
+	return nil
+}
@@ -3,7 +3,7 @@ package usecase
 import (
 	"fmt"
 
-	"dbupdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/internal/domain"
 )
 
 type InitMigrationUseCase struct {
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+type LockRepo interface {
+	// Returns false if the lock is held by another session
+	TryLock(ctx context.Context, lock *domain.Lock) (bool, error)
+
+	Unlock(ctx context.Context, lock *domain.Lock) error
+
+	// Returns true if another dbupdater holds the lock outside the locked database while restoring it
+	IsHeldForRestore(ctx context.Context, lock *domain.Lock) (bool, error)
+
+	// Returns nil if the lock is not held
+	GetLockHolder(ctx context.Context, lock *domain.Lock) (*domain.LockHolder, error)
+}
+
+// How often the lock is requested again while another dbupdater holds it
+const lockRetryInterval = time.Second
+
+type LockUseCase struct {
+	printer *helper.Printer
+	repo    LockRepo
+}
+
+func NewLockUseCase(repo LockRepo, printer *helper.Printer) *LockUseCase {
+	return &LockUseCase{
+		printer: printer,
+		repo:    repo,
+	}
+}
+
+// Takes the lock. If another dbupdater holds it, waits no longer than timeout, then returns domain.ErrLocked.
+func (uc *LockUseCase) Lock(ctx context.Context, lock *domain.Lock, timeout time.Duration) error {
+	uc.printer.ShowIfVerbose(fmt.Sprintf("The %s lock is taken...", lock.Name()))
+	deadline := time.Now().Add(timeout)
+	isWaitingShown := false
+	for {
+		isLocked, err := uc.tryLock(ctx, lock)
+		if err != nil {
+			return fmt.Errorf("error when taking the %s lock: %w", lock.Name(), err)
+		}
+		if isLocked {
+			uc.printer.ShowIfVerbose(fmt.Sprintf("The %s lock has been taken.", lock.Name()))
+			return nil
+		}
+
+		holder, err := uc.repo.GetLockHolder(ctx, lock)
+		if err != nil {
+			return fmt.Errorf("error when determining the holder of the %s lock: %w", lock.Name(), err)
+		}
+		holderDescription := "another session"
+		if holder != nil {
+			holderDescription = holder.String()
+		}
+
+		if !time.Now().Before(deadline) {
+			return domain.WithKind(domain.ErrLocked, fmt.Errorf("the database is locked by another dbupdater (%s), "+
+				"the lock has not been released in %s", holderDescription, timeout))
+		}
+		if !isWaitingShown {
+			uc.printer.Printf("The database is locked by another dbupdater (%s), waiting up to %s...\n", holderDescription, timeout)
+			isWaitingShown = true
+		}
+
+		wait := lockRetryInterval
+		if untilDeadline := time.Until(deadline); untilDeadline < wait {
+			wait = untilDeadline
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Takes the lock in a session that remains connected while the locked database is restored from a dump.
+// The lock in the locked database is held by this dbupdater, so it does not wait.
+func (uc *LockUseCase) LockForRestore(ctx context.Context, lock *domain.Lock) error {
+	isLocked, err := uc.repo.TryLock(ctx, lock)
+	if err != nil {
+		return fmt.Errorf("error when taking the %s lock for the restore: %w", lock.Name(), err)
+	}
+	if !isLocked {
+		return domain.WithKind(domain.ErrLocked, fmt.Errorf("the %s lock is held by another dbupdater restoring the database", lock.Name()))
+	}
+	uc.printer.ShowIfVerbose(fmt.Sprintf("The %s lock has been taken for the restore.", lock.Name()))
+	return nil
+}
+
+// The lock is not taken while another dbupdater restores the database, its lock in the database has been released
+func (uc *LockUseCase) tryLock(ctx context.Context, lock *domain.Lock) (bool, error) {
+	isLocked, err := uc.repo.TryLock(ctx, lock)
+	if err != nil || !isLocked {
+		return false, err
+	}
+	isHeldForRestore, err := uc.repo.IsHeldForRestore(ctx, lock)
+	if err == nil && !isHeldForRestore {
+		return true, nil
+	}
+	if errFromUnlock := uc.repo.Unlock(ctx, lock); errFromUnlock != nil {
+		return false, errFromUnlock
+	}
+	return false, err
+}
+
+func (uc *LockUseCase) Unlock(ctx context.Context, lock *domain.Lock) error {
+	if err := uc.repo.Unlock(ctx, lock); err != nil {
+		return fmt.Errorf("error when releasing the %s lock: %w", lock.Name(), err)
+	}
+	uc.printer.ShowIfVerbose(fmt.Sprintf("The %s lock has been released.", lock.Name()))
+	return nil
+}
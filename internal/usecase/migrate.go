@@ -2,11 +2,15 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
+	"time"
 
-	"dbupdater/helper"
-	"dbupdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type GetSqlFromRepo interface {
@@ -15,48 +19,169 @@ type GetSqlFromRepo interface {
 
 type ExecSqlByUsingRepo interface {
 	ExecSql(ctx context.Context, sql string) (err error)
+
+	// Executes COPY ... FROM stdin, data is the rows in the text format of COPY
+	CopyFromStdin(ctx context.Context, sql string, data string) error
+
+	IsInTransaction() bool
 }
 
 type MigrateUseCase struct {
-	isVerbose bool
-	getRepo   GetSqlFromRepo
-	execRepo  ExecSqlByUsingRepo
+	printer  *helper.Printer
+	getRepo  GetSqlFromRepo
+	execRepo ExecSqlByUsingRepo
 }
 
-func NewMigrateUseCase(getRepo GetSqlFromRepo, execRepo ExecSqlByUsingRepo, isVerbose bool) *MigrateUseCase {
+func NewMigrateUseCase(getRepo GetSqlFromRepo, execRepo ExecSqlByUsingRepo, printer *helper.Printer) *MigrateUseCase {
 	return &MigrateUseCase{
-		getRepo:   getRepo,
-		execRepo:  execRepo,
-		isVerbose: isVerbose,
+		getRepo:  getRepo,
+		execRepo: execRepo,
+		printer:  printer,
 	}
 }
 
-func (uc *MigrateUseCase) Migrate(ctx context.Context, pathToMigrations string, migrationsToMigrate []domain.MigrationGroup) error {
+// Returns the sql text that Migrate would execute for the migration
+func (uc *MigrateUseCase) GetSql(ctx context.Context, migration *domain.Migration) (string, error) {
+	sql, err := uc.getRepo.GetSqlFromMigration(ctx, migration)
+	if err != nil {
+		return "", fmt.Errorf("error when reading the %s %s migration: %w", migration.VersionDb.String(), migration.Name, err)
+	}
+	return sql, nil
+}
+
+// Returns the applied migrations in the order of application.
+// The error of the migration that has not been applied is domain.MigrationError.
+// With isByStatements the statements of every migration are executed one by one and the progress is shown.
+func (uc *MigrateUseCase) Migrate(ctx context.Context, migrationsToMigrate []domain.MigrationGroup, isByStatements bool,
+) ([]domain.AppliedMigration, error) {
 	colorGreen := "\033[32m"
 	colorRed := "\033[31m"
 	colorReset := "\033[0m"
 
-	fmt.Printf("Migrations started to apply...\n")
+	appliedMigrations := make([]domain.AppliedMigration, 0)
+	uc.printer.Printf("Migrations started to apply...\n")
 	for _, mg := range migrationsToMigrate {
 		migrations := mg.Migrations
 		for _, migration := range migrations {
 			mgVersionDbString := mg.VersionDb.String()
 			migrationName := migration.Name
-			ctx := context.Background()
 
-			helper.ShowIfVerbose(uc.isVerbose, fmt.Sprintf("Applied: %s %s", mgVersionDbString, migrationName))
+			uc.printer.ShowIfVerbose(fmt.Sprintf("Applied: %s %s", mgVersionDbString, migrationName))
+			if migration.IsNoTransaction && uc.execRepo.IsInTransaction() {
+				uc.printer.Errorf("Error applying migration: %s%s %s%s\n", colorRed, mgVersionDbString, migrationName, colorReset)
+				return nil, domain.NewMigrationError(&migration, fmt.Errorf("the migration is marked as no-transaction, but a transaction is open"))
+			}
 			sql, err := uc.getRepo.GetSqlFromMigration(ctx, &migration)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error applying migration: %s%s %s%s\n", colorRed, mgVersionDbString, migrationName, colorReset)
-				return err
+				uc.printer.Errorf("Error applying migration: %s%s %s%s\n", colorRed, mgVersionDbString, migrationName, colorReset)
+				return nil, domain.NewMigrationError(&migration, err)
 			}
-			if err := uc.execRepo.ExecSql(ctx, sql); err != nil {
-				fmt.Fprintf(os.Stderr, "Error applying migration: %s%s %s%s\n", colorRed, mgVersionDbString, migrationName, colorReset)
-				return err
+			appliedAt := time.Now()
+			execute := uc.execMigration
+			if isByStatements {
+				execute = uc.execMigrationByStatements
 			}
-			fmt.Println(fmt.Sprintf("Ready: %s%s %s%s", colorGreen, mgVersionDbString, migrationName, colorReset))
+			if migrationErr := execute(ctx, &migration, sql); migrationErr != nil {
+				uc.printer.Errorf("Error applying migration: %s%s %s%s\n", colorRed, mgVersionDbString, migrationName, colorReset)
+				var pgErr *pgconn.PgError
+				if errors.As(migrationErr, &pgErr) {
+					uc.showSqlError(pgErr, migrationErr)
+				}
+				return nil, migrationErr
+			}
+			migration := migration
+			appliedMigration := domain.NewAppliedMigration(&migration, sql, appliedAt, time.Since(appliedAt))
+			appliedMigrations = append(appliedMigrations, *appliedMigration)
+			uc.printer.Println(fmt.Sprintf("Ready: %s%s %s%s", colorGreen, mgVersionDbString, migrationName, colorReset))
 		}
 	}
-	fmt.Printf("Migrations have been applied.\n")
+	uc.printer.Printf("Migrations have been applied.\n")
+	return appliedMigrations, nil
+}
+
+// Executes the migration file as one multi-statement query
+func (uc *MigrateUseCase) execMigration(ctx context.Context, migration *domain.Migration, sql string) *domain.MigrationError {
+	if err := uc.execRepo.ExecSql(ctx, sql); err != nil {
+		return newExecMigrationError(migration, sql, 0, err)
+	}
 	return nil
 }
+
+// Executes the statements of the migration file one by one and shows the number and the duration of each one
+func (uc *MigrateUseCase) execMigrationByStatements(ctx context.Context, migration *domain.Migration, sql string) *domain.MigrationError {
+	statements := domain.SplitSqlStatements(sql)
+	for i, statement := range statements {
+		startedAt := time.Now()
+		var err error
+		if statement.IsCopyFromStdin {
+			err = uc.execRepo.CopyFromStdin(ctx, statement.Text, statement.CopyData)
+		} else {
+			err = uc.execRepo.ExecSql(ctx, statement.Text)
+		}
+		if err != nil {
+			migrationErr := newExecMigrationError(migration, sql, statement.Offset, err)
+			migrationErr.Statement = &domain.FailedStatement{
+				Number:    i + 1,
+				Count:     len(statements),
+				Statement: statement,
+			}
+			uc.printer.Errorf("Statement %d/%d at line %d has failed after %s\n", i+1, len(statements), statement.Line,
+				time.Since(startedAt).Round(time.Millisecond))
+			return migrationErr
+		}
+		uc.printer.Printf("  statement %d/%d at line %d: %s\n", i+1, len(statements), statement.Line,
+			time.Since(startedAt).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// The offset is the number of the first character of the executed sql in the migration file.
+// The position of the error reported by PostgreSQL is counted from it.
+func newExecMigrationError(migration *domain.Migration, sql string, offset int, err error) *domain.MigrationError {
+	migrationErr := domain.NewMigrationError(migration, err)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Position > 0 {
+		migrationErr.Position = domain.NewSqlPosition(sql, offset+int(pgErr.Position))
+	}
+	return migrationErr
+}
+
+// Shows the fields of the error reported by PostgreSQL and the lines of the migration file around the error
+func (uc *MigrateUseCase) showSqlError(pgErr *pgconn.PgError, migrationErr *domain.MigrationError) {
+	colorRed := "\033[31m"
+	colorReset := "\033[0m"
+
+	uc.printer.Errorf("%s: %s (SQLSTATE %s)\n", pgErr.Severity, pgErr.Message, pgErr.Code)
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{name: "DETAIL", value: pgErr.Detail},
+		{name: "HINT", value: pgErr.Hint},
+		{name: "SCHEMA", value: pgErr.SchemaName},
+		{name: "TABLE", value: pgErr.TableName},
+		{name: "COLUMN", value: pgErr.ColumnName},
+		{name: "CONSTRAINT", value: pgErr.ConstraintName},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			uc.printer.Errorf("%s: %s\n", field.name, field.value)
+		}
+	}
+
+	position := migrationErr.Position
+	if position == nil {
+		return
+	}
+	uc.printer.Errorf("At line %d, column %d of %s %s:\n", position.Line, position.Column,
+		migrationErr.Migration.VersionDb.String(), migrationErr.Migration.Name)
+	width := len(fmt.Sprint(position.Lines[len(position.Lines)-1].Number))
+	for _, line := range position.Lines {
+		if line.Number != position.Line {
+			uc.printer.Errorf("  %*d | %s\n", width, line.Number, line.Text)
+			continue
+		}
+		uc.printer.Errorf("%s> %*d | %s%s\n", colorRed, width, line.Number, line.Text, colorReset)
+		uc.printer.Errorf("  %s | %s%s^%s\n", strings.Repeat(" ", width), position.IndentOfColumn(), colorRed, colorReset)
+	}
+}
@@ -3,58 +3,81 @@ package usecase
 import (
 	"context"
 
-	"dbupdater/helper"
-	"dbupdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
 )
 
 type MigrationRepoForMigrationCurrent interface {
-	HasCurrentMigration(ctx context.Context, sqlForCheckMigration string) (bool, error)
-	GetCurrentMigration(ctx context.Context, sqlForGetMigration string) (*domain.Migration, error)
-	UpdateCurrentMigration(ctx context.Context, sqlForUpdateMigration string, lastAppliedMigration *domain.Migration) (err error)
+	HasCurrentMigration(ctx context.Context) (bool, error)
+	GetCurrentMigration(ctx context.Context) (*domain.Migration, error)
+	UpdateCurrentMigration(ctx context.Context, appliedMigrations []domain.AppliedMigration) (err error)
+	RevertCurrentMigration(ctx context.Context, revertedMigrations []domain.RevertedMigration) (err error)
+	RecordManualChanges(ctx context.Context, changes []domain.ManualChange) error
 }
 
 type MigrationCurrentUseCase struct {
-	isVerbose bool
-	repo      MigrationRepoForMigrationCurrent
+	printer *helper.Printer
+	repo    MigrationRepoForMigrationCurrent
 }
 
-func NewMigrationCurrentUseCase(repo MigrationRepoForMigrationCurrent, isVerbose bool) *MigrationCurrentUseCase {
+func NewMigrationCurrentUseCase(repo MigrationRepoForMigrationCurrent, printer *helper.Printer) *MigrationCurrentUseCase {
 	return &MigrationCurrentUseCase{
-		repo:      repo,
-		isVerbose: isVerbose,
+		repo:    repo,
+		printer: printer,
 	}
 }
 
-func (uc *MigrationCurrentUseCase) HasCurrentMigration(ctx context.Context, sqlForCheckMigration string) (bool, error) {
-	helper.ShowIfVerbose(uc.isVerbose, "Checking availability database version and last applied migration...")
-	isAvailable, err := uc.repo.HasCurrentMigration(ctx, sqlForCheckMigration)
+func (uc *MigrationCurrentUseCase) HasCurrentMigration(ctx context.Context) (bool, error) {
+	uc.printer.ShowIfVerbose("Checking availability database version and last applied migration...")
+	isAvailable, err := uc.repo.HasCurrentMigration(ctx)
 	if err != nil {
-		helper.ShowIfVerbose(uc.isVerbose, "The current version of the database is not available, error: "+err.Error())
+		uc.printer.ShowIfVerbose("The current version of the database is not available, error: " + err.Error())
 		return false, err
 	}
 	if isAvailable {
-		helper.ShowIfVerbose(uc.isVerbose, "The current version of the database is available.")
+		uc.printer.ShowIfVerbose("The current version of the database is available.")
 		return true, nil
 	}
-	helper.ShowIfVerbose(uc.isVerbose, "The current version of the database is not available.")
+	uc.printer.ShowIfVerbose("The current version of the database is not available.")
 	return false, nil
 }
 
-func (uc *MigrationCurrentUseCase) GetCurrentMigration(ctx context.Context, sqlForGetMigration string) (*domain.Migration, error) {
-	helper.ShowIfVerbose(uc.isVerbose, "Getting the database version and last applied migration...")
-	currentMigration, err := uc.repo.GetCurrentMigration(ctx, sqlForGetMigration)
+func (uc *MigrationCurrentUseCase) GetCurrentMigration(ctx context.Context) (*domain.Migration, error) {
+	uc.printer.ShowIfVerbose("Getting the database version and last applied migration...")
+	currentMigration, err := uc.repo.GetCurrentMigration(ctx)
 	if err != nil {
 		return nil, err
 	}
-	helper.ShowIfVerbose(uc.isVerbose, "VersionDb and last applied migration successfully retrieved.")
+	uc.printer.ShowIfVerbose("VersionDb and last applied migration successfully retrieved.")
 	return currentMigration, nil
 }
 
-func (uc *MigrationCurrentUseCase) UpdateCurrentMigration(ctx context.Context, sqlForUpdateMigration string, lastAppliedMigration *domain.Migration) (err error) {
-	helper.ShowIfVerbose(uc.isVerbose, "Information about the current database version and the last applied migration is updated...")
-	if err := uc.repo.UpdateCurrentMigration(ctx, sqlForUpdateMigration, lastAppliedMigration); err != nil {
+// The last of the applied migrations becomes the current one
+func (uc *MigrationCurrentUseCase) UpdateCurrentMigration(ctx context.Context, appliedMigrations []domain.AppliedMigration) (err error) {
+	uc.printer.ShowIfVerbose("Information about the current database version and the last applied migration is updated...")
+	if err := uc.repo.UpdateCurrentMigration(ctx, appliedMigrations); err != nil {
 		return err
 	}
-	helper.ShowIfVerbose(uc.isVerbose, "Information about the current database version and the last applied migration has been successfully updated.")
+	uc.printer.ShowIfVerbose("Information about the current database version and the last applied migration has been successfully updated.")
+	return nil
+}
+
+// The migration that became current after the last revert becomes the current one
+func (uc *MigrationCurrentUseCase) RevertCurrentMigration(ctx context.Context, revertedMigrations []domain.RevertedMigration) (err error) {
+	uc.printer.ShowIfVerbose("Information about the current database version and the last applied migration is updated...")
+	if err := uc.repo.RevertCurrentMigration(ctx, revertedMigrations); err != nil {
+		return err
+	}
+	uc.printer.ShowIfVerbose("Information about the current database version and the last applied migration has been successfully updated.")
+	return nil
+}
+
+// The migration of the last change becomes the current one, nothing is executed
+func (uc *MigrationCurrentUseCase) RecordManualChanges(ctx context.Context, changes []domain.ManualChange) error {
+	uc.printer.ShowIfVerbose("Information about the current database version and the last applied migration is updated...")
+	if err := uc.repo.RecordManualChanges(ctx, changes); err != nil {
+		return err
+	}
+	uc.printer.ShowIfVerbose("Information about the current database version and the last applied migration has been successfully updated.")
 	return nil
 }
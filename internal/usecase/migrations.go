@@ -2,11 +2,12 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
-	"dbupdater/helper"
+	"github.com/awsoremod/DBUpdater/helper"
 
-	"dbupdater/internal/domain"
+	"github.com/awsoremod/DBUpdater/internal/domain"
 )
 
 type MigrationRepoForMigrations interface {
@@ -18,15 +19,15 @@ type MigrationRepoForMigrations interface {
 }
 
 type MigrationsUseCase struct {
-	IsVerbose bool
-	repo      MigrationRepoForMigrations
+	printer *helper.Printer
+	repo    MigrationRepoForMigrations
 }
 
 // New -.
-func NewMigrationsUseCase(repo MigrationRepoForMigrations, isVerbose bool) *MigrationsUseCase {
+func NewMigrationsUseCase(repo MigrationRepoForMigrations, printer *helper.Printer) *MigrationsUseCase {
 	return &MigrationsUseCase{
-		IsVerbose: isVerbose,
-		repo:      repo,
+		printer: printer,
+		repo:    repo,
 	}
 }
 
@@ -34,7 +35,7 @@ func NewMigrationsUseCase(repo MigrationRepoForMigrations, isVerbose bool) *Migr
 // If isInitMod true, all migrations will be returned for a database version that is equal to the currentMigration database version.
 // Does not return a migrationGroup with empty migrations. The migrations in the migrationGroup are sorted as well.
 func (uc *MigrationsUseCase) GetUnappliedSortedMigrations(ctx context.Context, isInitMod bool, currentMigration *domain.Migration) ([]domain.MigrationGroup, error) {
-	helper.ShowIfVerbose(uc.IsVerbose, "The presence of new migrations in -migrations is analyzed...")
+	uc.printer.ShowIfVerbose("The presence of new migrations in -migrations is analyzed...")
 
 	unappliedMigrationGroups := make([]domain.MigrationGroup, 0)
 
@@ -64,10 +65,34 @@ func (uc *MigrationsUseCase) GetUnappliedSortedMigrations(ctx context.Context, i
 		}
 	}
 
-	helper.ShowIfVerbose(uc.IsVerbose, "Analysis successfully completed.")
+	uc.printer.ShowIfVerbose("Analysis successfully completed.")
 	return unappliedMigrationGroups, nil
 }
 
+// Returns the migration in -migrations. If the name is empty, the last migration of the version is returned.
+// Returns domain.ErrInvalidTarget if there is no such migration.
+func (uc *MigrationsUseCase) GetMigration(ctx context.Context, version *domain.VersionDb, name string) (*domain.Migration, error) {
+	mg, err := uc.repo.GetSortedMigrations(ctx, version)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("the %s version is not in -migrations", version.String()))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error when receiving the migrations of the %s version: %w", version.String(), err)
+	}
+	if len(mg.Migrations) == 0 {
+		return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("there are no migrations in the %s version", version.String()))
+	}
+	if name == "" {
+		return &mg.Migrations[len(mg.Migrations)-1], nil
+	}
+	for i := range mg.Migrations {
+		if mg.Migrations[i].Name == name {
+			return &mg.Migrations[i], nil
+		}
+	}
+	return nil, domain.WithKind(domain.ErrInvalidTarget, fmt.Errorf("the %s %s migration is not in -migrations", version.String(), name))
+}
+
 // Returns all migrations of the same database version that are newer than the specified migration. In ascending order.
 // If isInitMod true, all migrations will be returned for a database version that is equal to the currentMigration database version.
 func (uc *MigrationsUseCase) getUnappliedSortedMigrationsForVersion(ctx context.Context, isInitMod bool, currentMigration *domain.Migration) (*domain.MigrationGroup, error) {
@@ -97,3 +122,23 @@ func (uc *MigrationsUseCase) getUnappliedSortedMigrationsForVersion(ctx context.
 
 	return domain.NewMigrationGroup(currentMigration.VersionDb, unappliedMigrations), nil
 }
+
+// Returns migrations of all database versions in ascending order. Versions without migrations are skipped.
+func getAllSortedMigrations(ctx context.Context, repo MigrationRepoForMigrations) ([]domain.MigrationGroup, error) {
+	versions, err := repo.GetSortedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sortedMigrationGroups := make([]domain.MigrationGroup, 0, len(versions))
+	for _, version := range versions {
+		mg, err := repo.GetSortedMigrations(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		if len(mg.Migrations) != 0 {
+			sortedMigrationGroups = append(sortedMigrationGroups, *mg)
+		}
+	}
+	return sortedMigrationGroups, nil
+}
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+type MigrationRepoForRollback interface {
+	// Sorted migrations that start with 0001 are expected
+	GetSortedMigrations(ctx context.Context, version *domain.VersionDb) (*domain.MigrationGroup, error)
+
+	// Expect sorted versions that are not repeated
+	GetSortedVersions(ctx context.Context) ([]*domain.VersionDb, error)
+
+	GetSqlFromDownMigration(ctx context.Context, migration *domain.Migration) (string, error)
+}
+
+type HistoryRepoForRollback interface {
+	// Returns the migrations recorded with skip and not applied after it
+	GetSkippedMigrations(ctx context.Context) ([]domain.Migration, error)
+}
+
+type RollbackUseCase struct {
+	printer  *helper.Printer
+	repo     MigrationRepoForRollback
+	execRepo ExecSqlByUsingRepo
+
+	// nil in legacy mode, the skipped migrations are not recorded
+	historyRepo HistoryRepoForRollback
+}
+
+func NewRollbackUseCase(repo MigrationRepoForRollback, execRepo ExecSqlByUsingRepo, historyRepo HistoryRepoForRollback,
+	printer *helper.Printer,
+) *RollbackUseCase {
+	return &RollbackUseCase{
+		printer:     printer,
+		repo:        repo,
+		execRepo:    execRepo,
+		historyRepo: historyRepo,
+	}
+}
+
+// Returns the migrations that must be reverted to return from the current migration to the target one, in the order of reverting:
+// versions in descending order, migrations inside a version in descending order. The target migration is not reverted.
+// If targetName is empty, the target is the last migration of targetVersion.
+// Every returned migration must have a down script, except the skipped ones: they have not been executed,
+// their down scripts are not executed either.
+func (uc *RollbackUseCase) GetMigrationsToRollback(ctx context.Context, currentMigration *domain.Migration,
+	targetVersion *domain.VersionDb, targetName string,
+) ([]domain.MigrationGroup, *domain.Migration, error) {
+	uc.printer.ShowIfVerbose("Migrations to roll back are determined...")
+	sortedMigrationGroups, err := getAllSortedMigrations(ctx, uc.repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targetMigration, err := getTargetMigration(sortedMigrationGroups, targetVersion, targetName)
+	if err != nil {
+		return nil, nil, err
+	}
+	skippedMigrations, err := uc.getSkippedMigrations(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error when receiving the skipped migrations: %w", err)
+	}
+
+	currentIndexMigrationGroup, currentIndexMigration := domain.IndicesMigrationInMigrationGroups(sortedMigrationGroups, currentMigration)
+	if currentIndexMigrationGroup == -1 || currentIndexMigration == -1 {
+		return nil, nil, fmt.Errorf("the current %s %s migration is not in the migrations directory",
+			currentMigration.VersionDb.String(), currentMigration.Name)
+	}
+	targetIndexMigrationGroup, targetIndexMigration := domain.IndicesMigrationInMigrationGroups(sortedMigrationGroups, targetMigration)
+
+	isTargetAfterCurrent := targetIndexMigrationGroup > currentIndexMigrationGroup ||
+		(targetIndexMigrationGroup == currentIndexMigrationGroup && targetIndexMigration > currentIndexMigration)
+	if isTargetAfterCurrent {
+		return nil, nil, fmt.Errorf("the %s %s migration has not been applied yet, it is impossible to roll back to it",
+			targetMigration.VersionDb.String(), targetMigration.Name)
+	}
+
+	migrationGroupsToRollback := make([]domain.MigrationGroup, 0)
+	withoutDown := make([]string, 0)
+	for iGroup := currentIndexMigrationGroup; iGroup >= targetIndexMigrationGroup; iGroup-- {
+		mg := sortedMigrationGroups[iGroup]
+
+		first := 0
+		if iGroup == targetIndexMigrationGroup {
+			first = targetIndexMigration + 1
+		}
+		last := len(mg.Migrations) - 1
+		if iGroup == currentIndexMigrationGroup {
+			last = currentIndexMigration
+		}
+
+		migrations := make([]domain.Migration, 0)
+		for i := last; i >= first; i-- {
+			migration := mg.Migrations[i]
+			for j := range skippedMigrations {
+				if skippedMigrations[j].IsEqual(&migration) {
+					migration.IsSkipped = true
+				}
+			}
+			if !migration.HasDown && !migration.IsSkipped {
+				withoutDown = append(withoutDown, mg.VersionDb.String()+" "+migration.Name)
+			}
+			migrations = append(migrations, migration)
+		}
+		if len(migrations) != 0 {
+			migrationGroupsToRollback = append(migrationGroupsToRollback, *domain.NewMigrationGroup(mg.VersionDb, migrations))
+		}
+	}
+	if len(withoutDown) != 0 {
+		return nil, nil, fmt.Errorf("there are no down scripts for the migrations: %s", strings.Join(withoutDown, ", "))
+	}
+
+	uc.printer.ShowIfVerbose("Migrations to roll back have been determined.")
+	return migrationGroupsToRollback, targetMigration, nil
+}
+
+// Executes the down scripts in the specified order. Returns the reverted migrations in the order of reverting.
+func (uc *RollbackUseCase) Rollback(ctx context.Context, migrationsToRollback []domain.MigrationGroup,
+	targetMigration *domain.Migration,
+) ([]domain.RevertedMigration, error) {
+	colorGreen := "\033[32m"
+	colorRed := "\033[31m"
+	colorReset := "\033[0m"
+
+	ordered := make([]domain.Migration, 0)
+	for _, mg := range migrationsToRollback {
+		ordered = append(ordered, mg.Migrations...)
+	}
+
+	revertedMigrations := make([]domain.RevertedMigration, 0, len(ordered))
+	uc.printer.Printf("Migrations started to roll back...\n")
+	for i := range ordered {
+		migration := ordered[i]
+		currentAfterRevert := targetMigration
+		if i+1 < len(ordered) {
+			currentAfterRevert = &ordered[i+1]
+		}
+
+		if migration.IsSkipped {
+			revertedMigrations = append(revertedMigrations, *domain.NewRevertedMigration(&migration, currentAfterRevert, time.Now(), 0))
+			uc.printer.Printf("Passed over: %s %s, it has been skipped, the down script is not executed\n",
+				migration.VersionDb.String(), migration.Name)
+			continue
+		}
+
+		uc.printer.ShowIfVerbose(fmt.Sprintf("Rolled back: %s %s", migration.VersionDb.String(), migration.Name))
+		if migration.IsDownNoTransaction && uc.execRepo.IsInTransaction() {
+			uc.printer.Errorf("Error rolling back migration: %s%s %s%s\n", colorRed, migration.VersionDb.String(), migration.Name, colorReset)
+			return nil, fmt.Errorf("the down script is marked as no-transaction, but a transaction is open")
+		}
+		sql, err := uc.repo.GetSqlFromDownMigration(ctx, &migration)
+		if err != nil {
+			uc.printer.Errorf("Error rolling back migration: %s%s %s%s\n", colorRed, migration.VersionDb.String(), migration.Name, colorReset)
+			return nil, err
+		}
+		revertedAt := time.Now()
+		if err := uc.execRepo.ExecSql(ctx, sql); err != nil {
+			uc.printer.Errorf("Error rolling back migration: %s%s %s%s\n", colorRed, migration.VersionDb.String(), migration.Name, colorReset)
+			return nil, err
+		}
+		revertedMigration := domain.NewRevertedMigration(&migration, currentAfterRevert, revertedAt, time.Since(revertedAt))
+		revertedMigrations = append(revertedMigrations, *revertedMigration)
+		uc.printer.Printf("Reverted: %s%s %s%s\n", colorGreen, migration.VersionDb.String(), migration.Name, colorReset)
+	}
+	uc.printer.Printf("Migrations have been rolled back.\n")
+	return revertedMigrations, nil
+}
+
+func (uc *RollbackUseCase) getSkippedMigrations(ctx context.Context) ([]domain.Migration, error) {
+	if uc.historyRepo == nil {
+		return nil, nil
+	}
+	return uc.historyRepo.GetSkippedMigrations(ctx)
+}
+
+// If targetName is empty, the target is the last migration of targetVersion
+func getTargetMigration(sortedMigrationGroups []domain.MigrationGroup, targetVersion *domain.VersionDb, targetName string) (*domain.Migration, error) {
+	if targetName == "" {
+		for _, mg := range sortedMigrationGroups {
+			if mg.VersionDb.Equal(targetVersion) {
+				return &mg.Migrations[len(mg.Migrations)-1], nil
+			}
+		}
+		return nil, fmt.Errorf("the %s version is not in the migrations directory", targetVersion.String())
+	}
+
+	targetMigration, err := domain.NewMigration(targetName, targetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect migration from -versiondb and -migration parameters: %w", err)
+	}
+	indexMigrationGroup, indexMigration := domain.IndicesMigrationInMigrationGroups(sortedMigrationGroups, targetMigration)
+	if indexMigrationGroup == -1 || indexMigration == -1 {
+		return nil, fmt.Errorf("the %s %s migration is not in the migrations directory", targetVersion.String(), targetName)
+	}
+	return &sortedMigrationGroups[indexMigrationGroup].Migrations[indexMigration], nil
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+type PlanRepo interface {
+	Save(ctx context.Context, path string, plan *domain.SavedPlan) error
+	Load(ctx context.Context, path string) (*domain.SavedPlan, error)
+}
+
+// Saves the plan made by plan and checks before applying it that neither the database nor the migration files have changed
+type SavedPlanUseCase struct {
+	printer  *helper.Printer
+	planRepo PlanRepo
+	getRepo  GetSqlFromRepo
+}
+
+func NewSavedPlanUseCase(planRepo PlanRepo, getRepo GetSqlFromRepo, printer *helper.Printer) *SavedPlanUseCase {
+	return &SavedPlanUseCase{
+		printer:  printer,
+		planRepo: planRepo,
+		getRepo:  getRepo,
+	}
+}
+
+// Saves the migrations together with the checksums of their files and the current migration of the database
+func (uc *SavedPlanUseCase) Save(ctx context.Context, path string, isInitMode bool, currentMigration *domain.Migration,
+	migrationsToMigrate []domain.MigrationGroup,
+) error {
+	plannedMigrations := make([]domain.PlannedMigration, 0)
+	for _, mg := range migrationsToMigrate {
+		for i := range mg.Migrations {
+			migration := mg.Migrations[i]
+			sql, err := uc.getRepo.GetSqlFromMigration(ctx, &migration)
+			if err != nil {
+				return fmt.Errorf("error when reading the %s %s migration: %w", migration.VersionDb.String(), migration.Name, err)
+			}
+			plannedMigrations = append(plannedMigrations, domain.PlannedMigration{
+				Migration: &migration,
+				Checksum:  domain.CalculateChecksum(sql),
+			})
+		}
+	}
+
+	plan := domain.NewSavedPlan(currentMigration, isInitMode, plannedMigrations)
+	if err := uc.planRepo.Save(ctx, path, plan); err != nil {
+		return err
+	}
+	uc.printer.Printf("The plan has been saved to %s\n", path)
+	return nil
+}
+
+func (uc *SavedPlanUseCase) Load(ctx context.Context, path string) (*domain.SavedPlan, error) {
+	plan, err := uc.planRepo.Load(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading the plan: %w", err)
+	}
+	uc.printer.ShowIfVerbose(fmt.Sprintf("The plan from %s made at %s is applied.", path, plan.CreatedAt.Format("2006-01-02 15:04:05")))
+	return plan, nil
+}
+
+// Returns domain.ErrPlanMismatch if the current migration of the database is not the one the plan was made for
+func (uc *SavedPlanUseCase) CheckCurrentMigration(plan *domain.SavedPlan, isInitMode bool, currentMigration *domain.Migration) error {
+	if plan.IsInitMode == isInitMode && plan.StartMigration.IsEqual(currentMigration) {
+		return nil
+	}
+	return domain.WithKind(domain.ErrPlanMismatch, fmt.Errorf("the plan was made for the current migration %s, the current migration of the database is %s",
+		describeStartMigration(plan.IsInitMode, plan.StartMigration), describeStartMigration(isInitMode, currentMigration)))
+}
+
+// Returns domain.ErrPlanMismatch if the migrations to apply or the content of their files differ from the plan.
+// Returns the sql text of the migrations that has been checked, it is executed instead of reading the files again.
+func (uc *SavedPlanUseCase) CheckMigrations(ctx context.Context, plan *domain.SavedPlan, migrationsToMigrate []domain.MigrationGroup,
+) (*VerifiedSql, error) {
+	// The key is the migration, see migrationKey
+	checksumsOfPlan := make(map[string]string, len(plan.Migrations))
+	for _, planned := range plan.Migrations {
+		checksumsOfPlan[migrationKey(planned.Migration)] = planned.Checksum
+	}
+
+	verifiedSql := &VerifiedSql{sqlOfMigrations: make(map[string]string)}
+	differences := make([]string, 0)
+	for _, mg := range migrationsToMigrate {
+		for j := range mg.Migrations {
+			migration := mg.Migrations[j]
+			checksum, isPlanned := checksumsOfPlan[migrationKey(&migration)]
+			if !isPlanned {
+				differences = append(differences, fmt.Sprintf("%s %s is not in the plan", migration.VersionDb.String(), migration.Name))
+				continue
+			}
+			delete(checksumsOfPlan, migrationKey(&migration))
+
+			sql, err := uc.getRepo.GetSqlFromMigration(ctx, &migration)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				differences = append(differences, fmt.Sprintf("%s %s has been deleted", migration.VersionDb.String(), migration.Name))
+			case err != nil:
+				return nil, fmt.Errorf("error when reading the %s %s migration: %w", migration.VersionDb.String(), migration.Name, err)
+			case domain.CalculateChecksum(sql) != checksum:
+				differences = append(differences, fmt.Sprintf("%s %s has been changed", migration.VersionDb.String(), migration.Name))
+			default:
+				verifiedSql.sqlOfMigrations[migrationKey(&migration)] = sql
+			}
+		}
+	}
+	for _, planned := range plan.Migrations {
+		if _, isLeft := checksumsOfPlan[migrationKey(planned.Migration)]; isLeft {
+			differences = append(differences, fmt.Sprintf("%s %s is not in the migrations available for updating",
+				planned.Migration.VersionDb.String(), planned.Migration.Name))
+		}
+	}
+
+	if len(differences) != 0 {
+		return nil, domain.WithKind(domain.ErrPlanMismatch, fmt.Errorf("the migrations differ from the plan: %s", strings.Join(differences, "; ")))
+	}
+	return verifiedSql, nil
+}
+
+// VerifiedSql is the sql text of the migrations of the plan read when checking the plan. It is passed to MigrateUseCase
+// instead of the migration files, so a file changed after the check is not executed.
+type VerifiedSql struct {
+	// The key is the migration, see migrationKey
+	sqlOfMigrations map[string]string
+}
+
+// Returns domain.ErrPlanMismatch for the migrations that have not been checked
+func (v *VerifiedSql) GetSqlFromMigration(_ context.Context, migration *domain.Migration) (string, error) {
+	sql, ok := v.sqlOfMigrations[migrationKey(migration)]
+	if !ok {
+		return "", domain.WithKind(domain.ErrPlanMismatch,
+			fmt.Errorf("%s %s has not been checked against the plan", migration.VersionDb.String(), migration.Name))
+	}
+	return sql, nil
+}
+
+// Example: v0.0.1 0002.CreateTable
+func migrationKey(migration *domain.Migration) string {
+	return migration.VersionDb.String() + " " + migration.Name
+}
+
+func describeStartMigration(isInitMode bool, migration *domain.Migration) string {
+	if isInitMode {
+		return "none (initialization mode)"
+	}
+	return fmt.Sprintf("%s %s", migration.VersionDb.String(), migration.Name)
+}
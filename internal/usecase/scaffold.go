@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+type MigrationRepoForScaffold interface {
+	// Returns the path of the created file, the existing file is not overwritten
+	CreateMigrationFile(ctx context.Context, migration *domain.Migration, header string) (string, error)
+	CreateDownMigrationFile(ctx context.Context, migration *domain.Migration, header string) (string, error)
+
+	// Returns the path of the created directory
+	CreateVersionDir(ctx context.Context, version *domain.VersionDb) (string, error)
+
+	ReadTemplate(ctx context.Context, path string) (string, error)
+}
+
+// Creates the next migration file or the next database version directory, so the numbering and the order of
+// migrations do not have to be maintained by hand
+type ScaffoldUseCase struct {
+	printer  *helper.Printer
+	readRepo MigrationRepoForMigrations
+	dirRepo  MigrationRepoForScaffold
+}
+
+func NewScaffoldUseCase(readRepo MigrationRepoForMigrations, dirRepo MigrationRepoForScaffold, printer *helper.Printer) *ScaffoldUseCase {
+	return &ScaffoldUseCase{
+		printer:  printer,
+		readRepo: readRepo,
+		dirRepo:  dirRepo,
+	}
+}
+
+// The header of new migrations if the template is not specified
+const defaultMigrationTemplate = "-- {{if .IsDown}}Reverts {{end}}{{.VersionDb}} {{.Name}}\n-- Created at {{.CreatedAt}}\n\n"
+
+// The name of a new migration without the number. Example: AddIndexOnUsers
+var newMigrationNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+const maxMigrationNumber = 9999
+
+// The values available in the template of the header
+type migrationTemplateData struct {
+	// Example: v0.0.4
+	VersionDb string
+
+	// Example: 0006.AddIndexOnUsers
+	Name string
+
+	// Example: 2006-01-02 15:04:05
+	CreatedAt string
+
+	IsDown bool
+}
+
+// Creates the migration with the number following the last migration of the version.
+// If version is nil, the migration is added to the latest version. Returns the paths of the created files.
+func (uc *ScaffoldUseCase) NewMigration(ctx context.Context, version *domain.VersionDb, name string, isDown bool,
+	isNoTransaction bool, pathToTemplate string,
+) ([]string, error) {
+	if !newMigrationNameRegexp.MatchString(name) {
+		return nil, domain.WithKind(domain.ErrInvalidParameters,
+			fmt.Errorf("wrong name of the migration: %s, only letters, digits, '_' and '-' are allowed", name))
+	}
+
+	if version == nil {
+		var err error
+		version, err = uc.latestVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if version == nil {
+			return nil, domain.WithKind(domain.ErrNotFound,
+				fmt.Errorf("there are no database versions in -migrations, create the first one with new-version"))
+		}
+	}
+
+	mg, err := uc.readRepo.GetSortedMigrations(ctx, version)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.WithKind(domain.ErrNotFound,
+			fmt.Errorf("the %s version is not in -migrations, create it with new-version", version.String()))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error when receiving the migrations of the %s version: %w", version.String(), err)
+	}
+	number := len(mg.Migrations) + 1
+	if number > maxMigrationNumber {
+		return nil, fmt.Errorf("the %s version already has %d migrations, create a new version with new-version",
+			version.String(), maxMigrationNumber)
+	}
+
+	migration, err := domain.NewMigration(fmt.Sprintf("%04d.%s", number, name), version)
+	if err != nil {
+		return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("wrong name of the migration: %s: %w", name, err))
+	}
+	migration.IsNoTransaction = isNoTransaction
+	migration.HasDown = isDown
+	migration.IsDownNoTransaction = isDown && isNoTransaction
+
+	headerTemplate, err := uc.getTemplate(ctx, pathToTemplate)
+	if err != nil {
+		return nil, err
+	}
+	data := migrationTemplateData{
+		VersionDb: version.String(),
+		Name:      migration.Name,
+		CreatedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	header, err := executeTemplate(headerTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, 2)
+	path, err := uc.dirRepo.CreateMigrationFile(ctx, migration, header)
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, path)
+	uc.printer.Printf("Created %s\n", path)
+
+	if isDown {
+		data.IsDown = true
+		downHeader, err := executeTemplate(headerTemplate, data)
+		if err != nil {
+			return paths, err
+		}
+		path, err := uc.dirRepo.CreateDownMigrationFile(ctx, migration, downHeader)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+		uc.printer.Printf("Created %s\n", path)
+	}
+	return paths, nil
+}
+
+// Creates the directory of the version following the latest version in -migrations with the help of next.
+// Without versions the zero version v0.0.0 is created, initialization mode starts from it. Returns the path of the created directory.
+func (uc *ScaffoldUseCase) NewVersion(ctx context.Context, next func(*domain.VersionDb) *domain.VersionDb) (string, error) {
+	latestVersion, err := uc.latestVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	var newVersion *domain.VersionDb
+	if latestVersion == nil {
+		newVersion, err = domain.NewVersionDb(stringZeroVersion)
+		if err != nil {
+			return "", fmt.Errorf("error when creating a version from %s: %w", stringZeroVersion, err)
+		}
+	} else {
+		newVersion = next(latestVersion)
+	}
+
+	path, err := uc.dirRepo.CreateVersionDir(ctx, newVersion)
+	if err != nil {
+		return "", err
+	}
+	uc.printer.Printf("Created %s\n", path)
+	return path, nil
+}
+
+// Returns nil if there are no versions
+func (uc *ScaffoldUseCase) latestVersion(ctx context.Context) (*domain.VersionDb, error) {
+	versions, err := uc.readRepo.GetSortedVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error when receiving the database versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	return versions[len(versions)-1], nil
+}
+
+func (uc *ScaffoldUseCase) getTemplate(ctx context.Context, pathToTemplate string) (*template.Template, error) {
+	text := defaultMigrationTemplate
+	if pathToTemplate != "" {
+		var err error
+		text, err = uc.dirRepo.ReadTemplate(ctx, pathToTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("error when reading the template: %w", err)
+		}
+	}
+
+	headerTemplate, err := template.New("migration").Parse(text)
+	if err != nil {
+		return nil, domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("wrong template %s: %w", pathToTemplate, err))
+	}
+	return headerTemplate, nil
+}
+
+func executeTemplate(headerTemplate *template.Template, data migrationTemplateData) (string, error) {
+	var header strings.Builder
+	if err := headerTemplate.Execute(&header, data); err != nil {
+		return "", domain.WithKind(domain.ErrInvalidParameters, fmt.Errorf("error when executing the template: %w", err))
+	}
+	return header.String(), nil
+}
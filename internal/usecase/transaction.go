@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/awsoremod/DBUpdater/helper"
+)
+
+type TransactionRepo interface {
+	BeginTransaction(ctx context.Context) error
+	CommitTransaction(ctx context.Context) error
+	RollbackTransaction(ctx context.Context) error
+}
+
+type TransactionUseCase struct {
+	printer *helper.Printer
+	repo    TransactionRepo
+}
+
+func NewTransactionUseCase(repo TransactionRepo, printer *helper.Printer) *TransactionUseCase {
+	return &TransactionUseCase{
+		printer: printer,
+		repo:    repo,
+	}
+}
+
+func (uc *TransactionUseCase) Begin(ctx context.Context) error {
+	uc.printer.ShowIfVerbose("The transaction begins...")
+	if err := uc.repo.BeginTransaction(ctx); err != nil {
+		return err
+	}
+	uc.printer.ShowIfVerbose("The transaction has begun.")
+	return nil
+}
+
+func (uc *TransactionUseCase) Commit(ctx context.Context) error {
+	uc.printer.ShowIfVerbose("The transaction is committed...")
+	if err := uc.repo.CommitTransaction(ctx); err != nil {
+		return err
+	}
+	uc.printer.ShowIfVerbose("The transaction has been committed.")
+	return nil
+}
+
+func (uc *TransactionUseCase) Rollback(ctx context.Context) error {
+	if err := uc.repo.RollbackTransaction(ctx); err != nil {
+		return err
+	}
+	uc.printer.Println("The transaction has been rolled back.")
+	return nil
+}
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+type HistoryRepoForVerify interface {
+	// Returns the last application of each migration that was applied with a checksum
+	GetAppliedMigrations(ctx context.Context) ([]domain.AppliedMigration, error)
+}
+
+// Compares the checksums recorded when applying migrations with the migration files
+type VerifyUseCase struct {
+	printer     *helper.Printer
+	historyRepo HistoryRepoForVerify
+	getRepo     GetSqlFromRepo
+}
+
+func NewVerifyUseCase(historyRepo HistoryRepoForVerify, getRepo GetSqlFromRepo, printer *helper.Printer) *VerifyUseCase {
+	return &VerifyUseCase{
+		printer:     printer,
+		historyRepo: historyRepo,
+		getRepo:     getRepo,
+	}
+}
+
+// Returns the applied migrations whose files have been changed or deleted, in ascending order.
+// Only migrations up to the current one inclusive are checked, the reverted migrations after it are skipped.
+func (uc *VerifyUseCase) Verify(ctx context.Context, currentMigration *domain.Migration) ([]domain.ChecksumMismatch, error) {
+	uc.printer.ShowIfVerbose("Checksums of applied migrations are checked...")
+	appliedMigrations, err := uc.historyRepo.GetAppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(appliedMigrations, func(i, j int) bool {
+		return appliedMigrations[j].Migration.IsAfter(appliedMigrations[i].Migration)
+	})
+
+	mismatches := make([]domain.ChecksumMismatch, 0)
+	for _, appliedMigration := range appliedMigrations {
+		if appliedMigration.Migration.IsAfter(currentMigration) {
+			continue
+		}
+
+		actualChecksum := ""
+		sql, err := uc.getRepo.GetSqlFromMigration(ctx, appliedMigration.Migration)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+		case err != nil:
+			return nil, fmt.Errorf("error when reading the %s %s migration: %w",
+				appliedMigration.Migration.VersionDb.String(), appliedMigration.Migration.Name, err)
+		default:
+			actualChecksum = domain.CalculateChecksum(sql)
+		}
+
+		if actualChecksum != appliedMigration.Checksum {
+			mismatch := domain.NewChecksumMismatch(appliedMigration.Migration, appliedMigration.Checksum, actualChecksum)
+			mismatches = append(mismatches, *mismatch)
+		}
+	}
+	uc.printer.ShowIfVerbose(fmt.Sprintf("Checksums of %d applied migrations have been checked.", len(appliedMigrations)))
+	return mismatches, nil
+}
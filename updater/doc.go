@@ -0,0 +1,17 @@
+// Package updater applies the migrations of dbupdater from Go applications, for example at startup of a service.
+// The migrations can be shipped inside the binary of the service with go:embed and WithMigrationsFS.
+//
+// Only the exported identifiers of this package are meant to be used, everything under internal/ may change at any time.
+//
+//	u, err := updater.New(updater.Connection{Host: "localhost", Port: "5432", DbName: "app", User: "app", Password: "secret"},
+//		updater.WithMigrationsDir("./migrations"),
+//		updater.WithRollbackStrategy(updater.RollbackTransaction),
+//		updater.WithLogger(log.Default()),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	if err := u.Up(ctx, updater.Target{}); err != nil {
+//		return err
+//	}
+package updater
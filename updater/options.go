@@ -0,0 +1,120 @@
+package updater
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/helper"
+)
+
+// Logger receives the messages that are shown by the command line utility. *log.Logger implements it.
+type Logger = helper.Logger
+
+// Ways to return the database to its previous state if an error occurs when applying migrations
+const (
+	// A dump is created before applying, the database is restored from it. pg_dump and pg_restore are required.
+	RollbackDump = config.RollbackDump
+
+	// All migrations and the update of the current migration are executed in one transaction
+	RollbackTransaction = config.RollbackTransaction
+
+	// Each database version is applied in its own transaction, the applied versions are kept
+	RollbackTransactionPerVersion = config.RollbackTransactionPerVersion
+)
+
+// Ways to store the current migration in the database
+const (
+	// TrackingLegacy if there are utils/*.sql files in the directory with migrations, otherwise TrackingHistory
+	TrackingAuto = config.TrackingAuto
+
+	// The history table owned by dbupdater
+	TrackingHistory = config.TrackingHistory
+
+	// User queries from utils/HasCurrentVersion.sql, utils/GetCurrentVersion.sql, utils/UpdateCurrentVersion.sql
+	TrackingLegacy = config.TrackingLegacy
+)
+
+// Option changes the default settings of the Updater
+type Option func(u *Updater)
+
+// Messages are written to the logger. By default they are discarded.
+func WithLogger(logger Logger) Option {
+	return func(u *Updater) {
+		u.logger = logger
+	}
+}
+
+// Detailed messages are written to the logger
+func WithVerbose(isVerbose bool) Option {
+	return func(u *Updater) {
+		u.parameters.IsVerbose = isVerbose
+	}
+}
+
+// One of RollbackDump, RollbackTransaction, RollbackTransactionPerVersion. By default RollbackDump.
+func WithRollbackStrategy(rollbackStrategy string) Option {
+	return func(u *Updater) {
+		u.parameters.RollbackStrategy = rollbackStrategy
+	}
+}
+
+// Paths to pg_dump and pg_restore for RollbackDump. Empty paths mean that the utilities are searched automatically.
+func WithDumpUtilities(pathToDumpUtility string, pathToRestoreUtility string) Option {
+	return func(u *Updater) {
+		u.dumpUtilities.PathToDumpUtility = pathToDumpUtility
+		u.dumpUtilities.PathToRestoreUtility = pathToRestoreUtility
+	}
+}
+
+// The directory with migration scripts or a .zip or .tar.gz archive with them.
+// WithMigrationsDir or WithMigrationsFS is required.
+func WithMigrationsDir(pathToMigrations string) Option {
+	return func(u *Updater) {
+		u.parameters.PathToMigrations = pathToMigrations
+	}
+}
+
+// Migration scripts from a file system, for example embedded with go:embed. The version directories must be in the root,
+// use fs.Sub for a subdirectory. WithMigrationsDir or WithMigrationsFS is required.
+func WithMigrationsFS(migrationsFS fs.FS) Option {
+	return func(u *Updater) {
+		u.parameters.MigrationsFS = migrationsFS
+	}
+}
+
+// One of TrackingAuto, TrackingHistory, TrackingLegacy. By default TrackingAuto.
+func WithTracking(trackingMode string) Option {
+	return func(u *Updater) {
+		u.parameters.TrackingMode = trackingMode
+	}
+}
+
+// The table in which the history of migrations is stored. By default dbupdater_history.
+func WithHistoryTable(historyTable string) Option {
+	return func(u *Updater) {
+		u.parameters.HistoryTable = historyTable
+	}
+}
+
+// How long Up waits if another dbupdater is changing the same database, then ErrLocked is returned.
+// 0 - do not wait. By default 1 minute.
+func WithLockTimeout(lockTimeout time.Duration) Option {
+	return func(u *Updater) {
+		u.parameters.LockTimeout = lockTimeout
+	}
+}
+
+// Apply migrations even if the files of applied migrations have been changed
+func WithIgnoreChecksums(isIgnoreChecksums bool) Option {
+	return func(u *Updater) {
+		u.parameters.IsIgnoreChecksums = isIgnoreChecksums
+	}
+}
+
+// Execute the statements of every migration one by one, the progress is written to the logger
+func WithStatements(isByStatements bool) Option {
+	return func(u *Updater) {
+		u.parameters.IsByStatements = isByStatements
+	}
+}
@@ -0,0 +1,231 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/awsoremod/DBUpdater/config"
+	"github.com/awsoremod/DBUpdater/helper"
+	"github.com/awsoremod/DBUpdater/internal/core"
+	"github.com/awsoremod/DBUpdater/internal/domain"
+)
+
+// The kind of the returned errors can be checked with errors.Is
+var (
+	// The directory with migrations, a migration file or a migration in the database is not found
+	ErrNotFound = domain.ErrNotFound
+
+	// The target is not in the directory with migrations or has already been applied
+	ErrInvalidTarget = domain.ErrInvalidTarget
+
+	// The migrations have not been applied, the database has been returned to its previous state
+	ErrApplyFailed = domain.ErrApplyFailed
+
+	// The failed migrations have been rolled back, the versions committed before them remain applied.
+	// Example: RollbackTransactionPerVersion
+	ErrPartiallyApplied = domain.ErrPartiallyApplied
+
+	// The migrations have not been applied and the database could not be restored from the dump
+	ErrRestoreFailed = domain.ErrRestoreFailed
+
+	// The files of applied migrations have been changed
+	ErrChecksumMismatch = domain.ErrChecksumMismatch
+
+	// Another dbupdater is changing the database, the lock has not been released in time
+	ErrLocked = domain.ErrLocked
+
+	// The connection to the database has not been established
+	ErrConnectionFailed = domain.ErrConnectionFailed
+
+	// The options are missing or cannot be used together
+	ErrInvalidParameters = domain.ErrInvalidParameters
+
+	// The current migration of the database or the migration files differ from the saved plan
+	ErrPlanMismatch = domain.ErrPlanMismatch
+
+	// The user has not confirmed the changes of the database
+	ErrNotConfirmed = domain.ErrNotConfirmed
+)
+
+// Connection is the parameters of the connection to the database.
+// The empty fields are taken from ConnString, then from the PG* environment variables,
+// the password file and the service file in the same way as libpq does.
+type Connection struct {
+	// A URL (postgres://user@host/db?sslmode=require) or libpq keyword/value pairs
+	ConnString string
+	// Several hosts are separated by commas, a value starting with a slash is the directory of the Unix-domain socket
+	Host     string
+	Port     string
+	DbName   string
+	User     string
+	Password string
+	Service  string
+
+	SslMode     string
+	SslRootCert string
+	SslCert     string
+	SslKey      string
+}
+
+// Target is the last migration to apply. The zero Target means all new migrations.
+// If only VersionDb is specified, the migrations are applied up to the last migration of the version.
+type Target struct {
+	// For example v1.2.0
+	VersionDb string
+
+	// For example 0003.AddIndex
+	Migration string
+}
+
+// Migration is a migration script of some database version
+type Migration struct {
+	VersionDb       string
+	Name            string
+	IsNoTransaction bool
+	HasDown         bool
+}
+
+// Status is the state of the database relative to the directory with migrations
+type Status struct {
+	// The database is not tracked yet, the current migration is the one before the first migration
+	IsInitMode bool
+
+	CurrentMigration Migration
+
+	// Migrations after the current one in ascending order
+	UnappliedMigrations []Migration
+}
+
+// Updater applies the migrations from the directory to the database.
+// Each call opens its own connection to the database, the Updater can be reused.
+type Updater struct {
+	dbEntry       config.DbEntry
+	parameters    config.Parameters
+	dumpUtilities config.DumpUtilities
+	logger        Logger
+}
+
+func New(connection Connection, opts ...Option) (*Updater, error) {
+	u := &Updater{
+		dbEntry: config.DbEntry{
+			ConnString:  connection.ConnString,
+			Host:        connection.Host,
+			Port:        connection.Port,
+			DbName:      connection.DbName,
+			User:        connection.User,
+			Password:    connection.Password,
+			Service:     connection.Service,
+			SslMode:     connection.SslMode,
+			SslRootCert: connection.SslRootCert,
+			SslCert:     connection.SslCert,
+			SslKey:      connection.SslKey,
+		},
+		parameters: config.Parameters{
+			TrackingMode:     config.TrackingAuto,
+			HistoryTable:     config.DefaultHistoryTable,
+			RollbackStrategy: config.RollbackDump,
+			LockTimeout:      config.DefaultLockTimeout,
+			OutputFormat:     config.OutputText,
+			Bump:             config.BumpPatch,
+		},
+		logger: log.New(io.Discard, "", 0),
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	if err := config.CheckParameters(&u.parameters); err != nil {
+		return nil, domain.WithKind(domain.ErrInvalidParameters, err)
+	}
+	if u.parameters.PathToMigrations == "" && u.parameters.MigrationsFS == nil {
+		return nil, domain.WithKind(domain.ErrInvalidParameters,
+			fmt.Errorf("specify the migration scripts with WithMigrationsDir or WithMigrationsFS"))
+	}
+	return u, nil
+}
+
+// Returns the current migration and the migrations available for updating
+func (u *Updater) Status(ctx context.Context) (*Status, error) {
+	runner, err := u.newRunner(ctx, config.CommandStatus, Target{})
+	if err != nil {
+		return nil, err
+	}
+	defer runner.Close(ctx)
+
+	status, err := runner.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{
+		IsInitMode:          status.IsInitMode,
+		CurrentMigration:    migrationDomainToPublic(status.CurrentMigration),
+		UnappliedMigrations: migrationGroupsDomainToPublic(status.UnappliedMigrations),
+	}, nil
+}
+
+// Returns the migrations that Up would apply in the order of applying. The database is not changed.
+func (u *Updater) Plan(ctx context.Context, target Target) ([]Migration, error) {
+	runner, err := u.newRunner(ctx, config.CommandPlan, target)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.Close(ctx)
+
+	migrationsToMigrate, err := runner.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migrationGroupsDomainToPublic(migrationsToMigrate), nil
+}
+
+// Applies the migrations up to the target. If the migrations have not been applied
+// and the database has been returned to its previous state, ErrApplyFailed is returned.
+// If the versions committed before the failed one remain applied, ErrPartiallyApplied is returned.
+// Several instances of the service can call Up at the same time, the migrations are applied by one of them.
+func (u *Updater) Up(ctx context.Context, target Target) error {
+	runner, err := u.newRunner(ctx, config.CommandUp, target)
+	if err != nil {
+		return err
+	}
+	defer runner.Close(ctx)
+
+	return runner.Up(ctx)
+}
+
+func (u *Updater) newRunner(ctx context.Context, command string, target Target) (*core.Runner, error) {
+	parameters := u.parameters
+	parameters.Command = command
+	parameters.StringVersionDb = target.VersionDb
+	parameters.StringNameMigration = target.Migration
+
+	cfg := &config.Config{
+		App: config.App{
+			Version: config.Version,
+		},
+		Parameters:    parameters,
+		DbEntry:       u.dbEntry,
+		DumpUtilities: u.dumpUtilities,
+	}
+	return core.NewRunner(ctx, cfg, helper.NewPrinter(u.logger, parameters.IsVerbose))
+}
+
+func migrationDomainToPublic(migration *domain.Migration) Migration {
+	return Migration{
+		VersionDb:       migration.VersionDb.String(),
+		Name:            migration.Name,
+		IsNoTransaction: migration.IsNoTransaction,
+		HasDown:         migration.HasDown,
+	}
+}
+
+func migrationGroupsDomainToPublic(migrationGroups []domain.MigrationGroup) []Migration {
+	migrations := make([]Migration, 0)
+	for _, mg := range migrationGroups {
+		for i := range mg.Migrations {
+			migrations = append(migrations, migrationDomainToPublic(&mg.Migrations[i]))
+		}
+	}
+	return migrations
+}